@@ -14,6 +14,7 @@ import (
 	"github.com/ana-tonic/gopher-social/internal/auth"
 	"github.com/ana-tonic/gopher-social/internal/env"
 	"github.com/ana-tonic/gopher-social/internal/mailer"
+	"github.com/ana-tonic/gopher-social/internal/media"
 	"github.com/ana-tonic/gopher-social/internal/ratelimiter"
 	"github.com/ana-tonic/gopher-social/internal/store"
 	"github.com/ana-tonic/gopher-social/internal/store/cache"
@@ -25,13 +26,18 @@ import (
 )
 
 type application struct {
-	config        config
-	store         store.Storage
-	cacheStorage  cache.Storage
-	logger        *zap.SugaredLogger
-	mailer        mailer.Client
-	authenticator auth.Authenticator
-	rateLimiter   ratelimiter.Limiter
+	config            config
+	store             store.Storage
+	cacheStorage      cache.Storage
+	logger            *zap.SugaredLogger
+	mailer            mailer.Client
+	authenticator     auth.Authenticator
+	rateLimiter       ratelimiter.Limiter
+	loginGuard        *ratelimiter.LoginGuard
+	invitationLimiter ratelimiter.Limiter
+	resetLimiter      ratelimiter.Limiter
+	oidcProviders     map[string]*auth.OIDCProvider
+	blobStore         media.BlobStore
 }
 
 type config struct {
@@ -44,6 +50,58 @@ type config struct {
 	auth        authConfig
 	redisCfg    redisConfig
 	rateLimiter ratelimiter.Config
+	loginGuard  ratelimiter.LoginConfig
+	invitation  invitationConfig
+	media       mediaConfig
+	deletion    deletionConfig
+	export      exportConfig
+	reactions   reactionConfig
+	comments    commentConfig
+}
+
+type commentConfig struct {
+	// maxDepth is the deepest a reply can be nested, top-level comments
+	// being at depth 0
+	maxDepth int
+}
+
+type reactionConfig struct {
+	// emojis users can react to posts and comments with
+	emojis []string
+}
+
+type exportConfig struct {
+	// exp is how long the download link of an export stays valid
+	exp           time.Duration
+	sweepInterval time.Duration
+}
+
+type deletionConfig struct {
+	// gracePeriod is how long a deactivated account can be restored by
+	// logging in before it is purged
+	gracePeriod time.Duration
+	// anonymize keeps the posts and comments of purged accounts under the
+	// deleted user instead of removing them
+	anonymize     bool
+	sweepInterval time.Duration
+}
+
+type mediaConfig struct {
+	// backend is "local" or "s3"
+	backend       string
+	dir           string
+	s3            media.S3Config
+	maxUploadSize int64
+	userQuota     int64
+	thumbnailSize int
+}
+
+type invitationConfig struct {
+	resendLimiter ratelimiter.Config
+	// retention is how long unactivated accounts are kept before the sweeper
+	// deletes them
+	retention     time.Duration
+	sweepInterval time.Duration
 }
 
 type redisConfig struct {
@@ -56,6 +114,17 @@ type redisConfig struct {
 type authConfig struct {
 	basic basicConfig
 	token tokenConfig
+	mfa   mfaConfig
+	// oidc holds the external identity providers keyed by the name used in
+	// the /authentication/oidc/{provider} routes
+	oidc map[string]auth.OIDCConfig
+}
+
+type mfaConfig struct {
+	issuer string
+	exp    time.Duration
+	// requiredLevel makes 2FA mandatory for roles at or above this level, 0 disables it
+	requiredLevel int
 }
 
 type basicConfig struct {
@@ -64,9 +133,12 @@ type basicConfig struct {
 }
 
 type tokenConfig struct {
-	secret string
-	exp    time.Duration
-	iss    string
+	secret           string
+	signingKey       string
+	verificationKeys []string
+	exp              time.Duration
+	refreshExp       time.Duration
+	iss              string
 }
 
 type mailConfig struct {
@@ -74,6 +146,9 @@ type mailConfig struct {
 	mailTrap  mailTrapConfig
 	fromEmail string
 	exp       time.Duration
+	resetExp  time.Duration
+	// resetLimiter bounds the password reset emails sent to one address
+	resetLimiter ratelimiter.Config
 }
 
 type mailTrapConfig struct {
@@ -106,7 +181,7 @@ func (app *application) mount() http.Handler {
 
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{env.GetString("CORS_ALLOWED_ORIGIN", "http://localhost:5174")},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: false,
@@ -127,6 +202,7 @@ func (app *application) mount() http.Handler {
 		// Operations
 		r.Get("/health", app.healthCheckHandler)
 		r.With(app.BasicAuthMiddleware()).Get("/debug/vars", expvar.Handler().ServeHTTP)
+		r.Get("/.well-known/jwks.json", app.jwksHandler)
 
 		// Public routes - no authentication required
 		docsURL := httpSwagger.URL("http://localhost:8080/v1/swagger/doc.json")
@@ -134,35 +210,112 @@ func (app *application) mount() http.Handler {
 
 		r.Route("/posts", func(r chi.Router) {
 			r.Use(app.AuthTokenMiddleware)
-			r.Post("/", app.createPostHandler)
+			r.With(app.requireScope(scopePostsWrite)).Post("/", app.createPostHandler)
 
 			r.Route("/{postID}", func(r chi.Router) {
 				r.Use(app.postsContextMiddleware)
-				r.Get("/", app.getPostHandler)
+				r.With(app.requireScope(scopePostsRead)).Get("/", app.getPostHandler)
+
+				r.With(app.requireScope(scopePostsWrite)).Patch("/", app.checkPostOwnership("moderator", app.updatePostHandler))
+				r.With(app.requireScope(scopePostsWrite)).Delete("/", app.checkPostOwnership("admin", app.deletePostHandler))
 
-				r.Patch("/", app.checkPostOwnership("moderator", app.updatePostHandler))
-				r.Delete("/", app.checkPostOwnership("admin", app.deletePostHandler))
+				r.With(app.requireScope(scopePostsWrite)).Put("/reactions", app.reactToPostHandler)
+				r.With(app.requireScope(scopePostsWrite)).Delete("/reactions", app.unreactToPostHandler)
+				r.With(app.requireScope(scopePostsWrite)).Post("/reposts", app.repostHandler)
+				r.With(app.requireScope(scopePostsWrite)).Delete("/reposts", app.unrepostHandler)
+
+				r.With(app.requireScope(scopePostsRead)).Get("/revisions", app.listRevisionsHandler)
+				r.With(app.requireScope(scopePostsRead)).Get("/revisions/diff", app.diffRevisionsHandler)
+				r.With(app.requireScope(scopePostsWrite)).Post("/revisions/{version}/restore", app.checkPostOwnership("moderator", app.restoreRevisionHandler))
 
 				r.Route("/comments", func(r chi.Router) {
-					r.Post("/", app.createCommentHandler)
+					r.With(app.requireScope(scopePostsRead)).Get("/", app.listCommentsHandler)
+					r.With(app.requireScope(scopeCommentsWrite)).Post("/", app.createCommentHandler)
+
+					r.Route("/{commentID}", func(r chi.Router) {
+						r.Use(app.commentsContextMiddleware)
+						r.With(app.requireScope(scopeCommentsWrite)).Patch("/", app.checkCommentOwnership("moderator", app.updateCommentHandler))
+						r.With(app.requireScope(scopeCommentsWrite)).Delete("/", app.checkCommentOwnership("admin", app.deleteCommentHandler))
+
+						r.With(app.requireScope(scopeCommentsWrite)).Put("/reactions", app.reactToCommentHandler)
+						r.With(app.requireScope(scopeCommentsWrite)).Delete("/reactions", app.unreactToCommentHandler)
+					})
 				})
 			})
 		})
 
+		r.Get("/exports/{token}", app.downloadExportHandler)
+
+		r.Route("/comments/{commentID}", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.With(app.requireScope(scopePostsRead)).Get("/replies", app.listRepliesHandler)
+		})
+
+		r.Route("/media", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.With(app.requireScope(scopeMediaWrite)).Post("/", app.uploadMediaHandler)
+
+			r.Route("/{mediaID}", func(r chi.Router) {
+				r.With(app.requireScope(scopeMediaRead)).Get("/", app.getMediaHandler)
+				r.With(app.requireScope(scopeMediaRead)).Get("/thumbnail", app.getMediaThumbnailHandler)
+				r.With(app.requireScope(scopeMediaWrite)).Delete("/", app.deleteMediaHandler)
+			})
+		})
+
 		r.Route("/users", func(r chi.Router) {
 			r.Put("/activate/{token}", app.activateUserHandler)
+			r.Put("/email/confirm/{token}", app.confirmEmailHandler)
 
-			r.Route("/{userID}", func(r chi.Router) {
+			r.Route("/me", func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
-				r.Get("/", app.getUserHandler)
 
-				r.Put("/follow", app.followUserHandler)
-				r.Put("/unfollow", app.unfollowUserHandler)
+				r.With(app.requireScope(scopeUsersRead)).Get("/", app.getCurrentUserHandler)
+				r.With(app.requireScope(scopeUsersWrite)).Patch("/", app.updateProfileHandler)
+				r.With(app.requireUserToken).Delete("/", app.deleteAccountHandler)
+				r.With(app.requireUserToken).Post("/export", app.requestExportHandler)
+				r.With(app.requireScope(scopeUsersRead)).Get("/suggestions", app.getSuggestionsHandler)
+				r.With(app.requireUserToken).Patch("/email", app.changeEmailHandler)
+
+				r.Route("/follow-requests", func(r chi.Router) {
+					r.With(app.requireScope(scopeUsersRead)).Get("/", app.listFollowRequestsHandler)
+					r.With(app.requireScope(scopeUsersWrite)).Put("/{userID}", app.approveFollowRequestHandler)
+					r.With(app.requireScope(scopeUsersWrite)).Delete("/{userID}", app.rejectFollowRequestHandler)
+				})
+
+				r.Route("/sessions", func(r chi.Router) {
+					r.Use(app.requireUserToken)
+					r.Get("/", app.listSessionsHandler)
+					r.Delete("/{sessionID}", app.revokeSessionHandler)
+				})
+
+				r.Route("/api-keys", func(r chi.Router) {
+					r.Use(app.requireUserToken)
+					r.Get("/", app.listAPIKeysHandler)
+					r.Post("/", app.createAPIKeyHandler)
+					r.Delete("/{keyID}", app.revokeAPIKeyHandler)
+				})
+			})
+
+			r.Route("/{userID}", func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				r.With(app.requireScope(scopeUsersRead)).Get("/", app.getUserHandler)
+				r.With(app.requireScope(scopeUsersRead)).Get("/followers", app.getFollowersHandler)
+				r.With(app.requireScope(scopeUsersRead)).Get("/following", app.getFollowingHandler)
+				r.With(app.requireScope(scopeFeedRead)).Get("/posts", app.getUserPostsHandler)
+
+				r.With(app.requireScope(scopeUsersWrite)).Put("/follow", app.followUserHandler)
+				r.With(app.requireScope(scopeUsersWrite)).Put("/unfollow", app.unfollowUserHandler)
+				r.With(app.requireScope(scopeUsersWrite)).Put("/block", app.blockUserHandler)
+				r.With(app.requireScope(scopeUsersWrite)).Delete("/block", app.unblockUserHandler)
+				r.With(app.requireScope(scopeUsersWrite)).Put("/mute", app.muteUserHandler)
+				r.With(app.requireScope(scopeUsersWrite)).Delete("/mute", app.unmuteUserHandler)
+				r.With(app.requireUserToken, app.requireRole("admin")).Put("/unlock", app.unlockUserHandler)
 			})
 
 			r.Group(func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
-				r.Get("/feed", app.getUserFeedHandler)
+				r.With(app.requireScope(scopeFeedRead)).Get("/feed", app.getUserFeedHandler)
+				r.With(app.requireScope(scopeUsersRead)).Get("/search", app.searchUsersHandler)
 			})
 		})
 
@@ -170,6 +323,27 @@ func (app *application) mount() http.Handler {
 		r.Route("/authentication", func(r chi.Router) {
 			r.Post("/user", app.registerUserHandler)
 			r.Post("/token", app.createTokenHandler)
+			r.Post("/token/mfa", app.verifyMFAHandler)
+			r.Post("/refresh", app.refreshTokenHandler)
+			r.Post("/logout", app.logoutHandler)
+			r.Post("/invitation/resend", app.resendInvitationHandler)
+
+			r.Route("/password", func(r chi.Router) {
+				r.Post("/forgot", app.forgotPasswordHandler)
+				r.Post("/reset", app.resetPasswordHandler)
+			})
+
+			r.Route("/oidc/{provider}", func(r chi.Router) {
+				r.Get("/start", app.startOIDCLoginHandler)
+				r.Get("/callback", app.oidcCallbackHandler)
+			})
+
+			r.Route("/mfa/totp", func(r chi.Router) {
+				r.Use(app.MFAEnrollmentMiddleware)
+				r.Post("/", app.enrollTOTPHandler)
+				r.Post("/confirm", app.confirmTOTPHandler)
+				r.Delete("/", app.disableTOTPHandler)
+			})
 		})
 	})
 
@@ -187,6 +361,21 @@ func (app *application) run(mux http.Handler) error {
 
 	shutdown := make(chan error)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if app.config.invitation.sweepInterval > 0 {
+		go app.sweepUnactivatedUsers(ctx)
+	}
+
+	if app.config.deletion.sweepInterval > 0 {
+		go app.purgeDeletedUsers(ctx)
+	}
+
+	if app.config.export.sweepInterval > 0 {
+		go app.sweepExpiredExports(ctx)
+	}
+
 	go func() {
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
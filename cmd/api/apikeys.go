@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type apiKeyKey string
+
+const apiKeyCtx apiKeyKey = "apiKey"
+
+// apiKeyTouchInterval limits how often a key's last used time is written
+// while it is in use
+const apiKeyTouchInterval = time.Minute
+
+// Scopes an API key can be granted. Requests authenticated with a user token
+// are not restricted by scopes.
+const (
+	scopePostsRead     = "posts:read"
+	scopePostsWrite    = "posts:write"
+	scopeCommentsWrite = "comments:write"
+	scopeFeedRead      = "feed:read"
+	scopeUsersRead     = "users:read"
+	scopeUsersWrite    = "users:write"
+	scopeMediaRead     = "media:read"
+	scopeMediaWrite    = "media:write"
+)
+
+const apiKeyPrefix = "gsk_"
+
+type CreateAPIKeyPayload struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=posts:read posts:write comments:write feed:read users:read users:write media:read media:write"`
+	ExpiresInDays *int     `json:"expires_in_days" validate:"omitempty,gte=1,lte=365"`
+}
+
+type APIKeyWithToken struct {
+	*store.APIKey `json:"api_key"`
+	Token         string `json:"token"`
+}
+
+// CreateAPIKeyHandler godoc
+//
+//	@Summary		Creates an API key
+//	@Description	Creates a scoped API key for the authenticated user. The token is only returned once
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateAPIKeyPayload	true	"API key"
+//	@Success		201		{object}	APIKeyWithToken
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/api-keys [post]
+func (app *application) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateAPIKeyPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	token := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := &store.APIKey{
+		UserID: user.ID,
+		Name:   payload.Name,
+		Prefix: token[:len(apiKeyPrefix)+8],
+		Scopes: payload.Scopes,
+	}
+
+	var expiry *time.Time
+	if payload.ExpiresInDays != nil {
+		t := time.Now().AddDate(0, 0, *payload.ExpiresInDays)
+		expiry = &t
+	}
+
+	if err := app.store.APIKeys.Create(r.Context(), key, token, expiry); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, APIKeyWithToken{APIKey: key, Token: token}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ListAPIKeysHandler godoc
+//
+//	@Summary		Lists API keys
+//	@Description	Lists the authenticated user's API keys
+//	@Tags			users
+//	@Produce		json
+//	@Success		200	{object}	[]store.APIKey
+//	@Failure		403	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/api-keys [get]
+func (app *application) listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	keys, err := app.store.APIKeys.GetByUserID(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, keys); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// RevokeAPIKeyHandler godoc
+//
+//	@Summary		Revokes an API key
+//	@Description	Revokes one of the authenticated user's API keys
+//	@Tags			users
+//	@Param			keyID	path		int		true	"API key ID"
+//	@Success		204		{string}	string	"API key revoked"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/api-keys/{keyID} [delete]
+func (app *application) revokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	keyID, err := strconv.ParseInt(chi.URLParam(r, "keyID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	if err := app.store.APIKeys.Delete(r.Context(), keyID, user.ID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func getAPIKeyFromContext(r *http.Request) *store.APIKey {
+	key, _ := r.Context().Value(apiKeyCtx).(*store.APIKey)
+	return key
+}
+
+// touchAPIKey updates the key's last used time, at most once per
+// apiKeyTouchInterval.
+func (app *application) touchAPIKey(ctx context.Context, key *store.APIKey) {
+	if key.LastUsedAt != nil {
+		lastUsed, err := time.Parse(time.RFC3339, *key.LastUsedAt)
+		if err == nil && time.Since(lastUsed) < apiKeyTouchInterval {
+			return
+		}
+	}
+
+	if err := app.store.APIKeys.Touch(ctx, key); err != nil && err != store.ErrNotFound {
+		app.logger.Errorw("error updating api key usage", "api_key_id", key.ID, "error", err)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	t.Run("should allow routes within the key scopes", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "ApiKey "+store.MockAPIKeyToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should forbid routes outside of the key scopes", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPut, "/v1/users/2/follow", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "ApiKey "+store.MockAPIKeyToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("should not let a key manage API keys", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/me/api-keys", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "ApiKey "+store.MockAPIKeyToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("should reject unknown keys", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "ApiKey nope")
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should reject keys of users who have yet to enroll in mandatory 2FA", func(t *testing.T) {
+		app := newTestApplication(t, config{auth: authConfig{mfa: mfaConfig{requiredLevel: 2}}})
+		app.store.Users = &moderatorUserStore{}
+		mux := app.mount()
+
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "ApiKey "+store.MockAPIKeyToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("should record the use of a key at most once per interval", func(t *testing.T) {
+		for _, tc := range []struct {
+			lastUsed time.Duration
+			touched  bool
+		}{
+			{lastUsed: time.Second, touched: false},
+			{lastUsed: apiKeyTouchInterval * 2, touched: true},
+		} {
+			app := newTestApplication(t, config{})
+			keys := &usedAPIKeyStore{lastUsed: time.Now().Add(-tc.lastUsed).Format(time.RFC3339)}
+			app.store.APIKeys = keys
+
+			req, err := http.NewRequest(http.MethodGet, "/v1/users/1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "ApiKey "+store.MockAPIKeyToken)
+
+			rr := executeRequest(req, app.mount())
+			checkResponseCode(t, http.StatusOK, rr.Code)
+
+			if keys.touched != tc.touched {
+				t.Errorf("last used %v ago: expected touched %v, got %v", tc.lastUsed, tc.touched, keys.touched)
+			}
+		}
+	})
+}
+
+// usedAPIKeyStore returns the mock key as last used at lastUsed and records
+// whether its use is written.
+type usedAPIKeyStore struct {
+	store.MockAPIKeyStore
+	lastUsed string
+	touched  bool
+}
+
+func (m *usedAPIKeyStore) GetByToken(ctx context.Context, token string) (*store.APIKey, error) {
+	key, err := m.MockAPIKeyStore.GetByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	key.LastUsedAt = &m.lastUsed
+	return key, nil
+}
+
+func (m *usedAPIKeyStore) Touch(ctx context.Context, key *store.APIKey) error {
+	m.touched = true
+	return nil
+}
+
+// moderatorUserStore returns users whose role is high enough to require 2FA.
+type moderatorUserStore struct {
+	store.MockUserStore
+}
+
+func (m *moderatorUserStore) GetByID(ctx context.Context, userID int64) (*store.User, error) {
+	return &store.User{ID: userID, Role: store.Role{Name: "moderator", Level: 2}}, nil
+}
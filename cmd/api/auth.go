@@ -3,18 +3,16 @@ package main
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"fmt"
 	"net/http"
 	"time"
 
-	"github.com/ana-tonic/gopher-social/internal/mailer"
 	"github.com/ana-tonic/gopher-social/internal/store"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
 type RegisterUserPayload struct {
-	Username string `json:"username" validate:"required,max=100"`
+	Username string `json:"username" validate:"required,max=100,notreserved"`
 	Email    string `json:"email" validate:"required,email,max=255"`
 	Password string `json:"password" validate:"required,min=3,max=72"`
 }
@@ -90,19 +88,8 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		Token: plainToken,
 	}
 
-	activationURL := fmt.Sprintf("%s/confirm/%s", app.config.frontendURL, plainToken)
-
-	isProdEnv := app.config.env == "production"
-	vars := struct {
-		Username      string
-		ActivationURL string
-	}{
-		Username:      user.Username,
-		ActivationURL: activationURL,
-	}
-
 	// send mail
-	status, err := app.mailer.Send(mailer.UserWelcomeTemplate, user.Username, user.Email, vars, !isProdEnv)
+	status, err := app.sendInvitationEmail(user, plainToken)
 	if err != nil {
 		app.logger.Errorw("error sending welcome email", "error", err)
 
@@ -130,14 +117,17 @@ type CreateUserTokenPayload struct {
 // CreateTokenHandler godoc
 //
 //	@Summary		Creates a token
-//	@Description	Creates a token for a user
+//	@Description	Creates a token for a user. Logging in to an account awaiting deletion restores it
 //	@Tags			authentication
 //	@Accept			json
 //	@Produce		json
 //	@Param			payload	body		CreateUserTokenPayload	true	"User credentials"
-//	@Success		200		{string}	string					"Token"
+//	@Success		201		{object}	TokenPair				"Token pair"
+//	@Success		202		{object}	MFAChallenge			"Second factor required"
 //	@Failure		400		{object}	error
 //	@Failure		401		{object}	error
+//	@Failure		423		{object}	error	"Account locked"
+//	@Failure		429		{object}	error	"Too many failed attempts, or the client IP is locked"
 //	@Failure		500		{object}	error
 //	@Router			/authentication/token [post]
 func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Request) {
@@ -153,10 +143,21 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	user, err := app.store.Users.GetByEmail(r.Context(), payload.Email)
+	verdict, ok := app.checkLogin(w, r, payload.Email)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	user, err := app.store.Users.GetByEmail(ctx, payload.Email)
+	if err == store.ErrNotFound {
+		user, err = app.store.Users.GetDeactivatedByEmail(ctx, payload.Email)
+	}
 	if err != nil {
 		switch err {
 		case store.ErrNotFound:
+			app.loginFailed(verdict, payload.Email, nil)
 			app.unauthorizedErrorResponse(w, r, err)
 		default:
 			app.internalServerError(w, r, err)
@@ -165,26 +166,211 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	if err := user.Password.Compare(payload.Password); err != nil {
+		app.loginFailed(verdict, payload.Email, user)
 		app.unauthorizedErrorResponse(w, r, err)
 		return
 	}
 
-	claims := jwt.MapClaims{
-		"sub": user.ID,
-		"exp": time.Now().Add(app.config.auth.token.exp).Unix(),
-		"iat": time.Now().Unix(),
-		"nbf": time.Now().Unix(),
-		"iss": app.config.auth.token.iss,
-		"aud": app.config.auth.token.iss,
+	// with 2FA the failures only reset, and a deactivated account is only
+	// restored, once the second factor is verified
+	if user.TOTPEnabled {
+		app.loginPending(ctx, r, user.Email)
+
+		challenge, err := app.newMFAChallenge(user)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if err := app.jsonResponse(w, http.StatusAccepted, challenge); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
 	}
 
-	token, err := app.authenticator.GenerateToken(claims)
+	if !app.reactivateUser(w, r, user) {
+		return
+	}
+
+	app.loginSucceeded(ctx, r, user.Email)
+
+	tokens, err := app.startSession(r, user.ID)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusCreated, token); err != nil {
+	if err := app.jsonResponse(w, http.StatusCreated, tokens); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
+
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refresh_token" validate:"required,max=255"`
+}
+
+// RefreshTokenHandler godoc
+//
+//	@Summary		Refreshes a token
+//	@Description	Exchanges a refresh token for a new token pair. Each refresh token can be used once; reusing one revokes its session
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		RefreshTokenPayload	true	"Refresh token"
+//	@Success		201		{object}	TokenPair
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/authentication/refresh [post]
+func (app *application) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var payload RefreshTokenPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	refreshToken := uuid.New().String()
+
+	session, err := app.store.Sessions.Rotate(ctx, payload.RefreshToken, refreshToken, app.config.auth.token.refreshExp)
+	if err != nil {
+		switch err {
+		case store.ErrTokenReused:
+			app.logger.Warnw("refresh token reuse detected, session revoked", "path", r.URL.Path)
+			app.forgetSession(ctx, session.ID)
+			app.unauthorizedErrorResponse(w, r, err)
+		case store.ErrNotFound:
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// the user may have been deleted or deactivated since the session started
+	if _, err := app.store.Users.GetByID(ctx, session.UserID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.touchSession(ctx, session, r)
+
+	tokens, err := app.newTokenPair(session, refreshToken)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, tokens); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// LogoutHandler godoc
+//
+//	@Summary		Logs out
+//	@Description	Revokes the session the refresh token belongs to, invalidating all of its tokens
+//	@Tags			authentication
+//	@Accept			json
+//	@Param			payload	body		RefreshTokenPayload	true	"Refresh token"
+//	@Success		204		{string}	string				"Session revoked"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/authentication/logout [post]
+func (app *application) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	var payload RefreshTokenPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	session, err := app.store.Sessions.GetByRefreshToken(ctx, payload.RefreshToken)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.store.Sessions.Revoke(ctx, session.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.forgetSession(ctx, session.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type TokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// startSession opens a new session for the user on the requesting device and
+// returns its first token pair.
+func (app *application) startSession(r *http.Request, userID int64) (*TokenPair, error) {
+	session := &store.Session{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		UserAgent: userAgent(r),
+		IP:        clientIP(r),
+	}
+	refreshToken := uuid.New().String()
+
+	if err := app.store.Sessions.Create(r.Context(), session, refreshToken, app.config.auth.token.refreshExp); err != nil {
+		return nil, err
+	}
+
+	return app.newTokenPair(session, refreshToken)
+}
+
+// newTokenPair signs a short-lived access token bound to the session and pairs
+// it with the session's current refresh token.
+func (app *application) newTokenPair(session *store.Session, refreshToken string) (*TokenPair, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub": session.UserID,
+		"sid": session.ID,
+		"exp": now.Add(app.config.auth.token.exp).Unix(),
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"iss": app.config.auth.token.iss,
+		"aud": app.config.auth.token.iss,
+	}
+
+	token, err := app.authenticator.GenerateToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(app.config.auth.token.exp.Seconds()),
+	}, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/ana-tonic/gopher-social/internal/store/cache"
+)
+
+func TestRefreshTokens(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	post := func(t *testing.T, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return executeRequest(req, mux)
+	}
+
+	t.Run("should rotate the refresh token", func(t *testing.T) {
+		rr := post(t, "/v1/authentication/refresh", `{"refresh_token":"`+store.MockRefreshToken+`"}`)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		var body struct {
+			Data TokenPair `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if body.Data.Token == "" {
+			t.Error("expected an access token")
+		}
+		if body.Data.RefreshToken == "" || body.Data.RefreshToken == store.MockRefreshToken {
+			t.Errorf("expected a new refresh token, got %q", body.Data.RefreshToken)
+		}
+	})
+
+	t.Run("should reject a reused refresh token", func(t *testing.T) {
+		rr := post(t, "/v1/authentication/refresh", `{"refresh_token":"`+store.MockReusedRefreshToken+`"}`)
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should reject an unknown refresh token", func(t *testing.T) {
+		rr := post(t, "/v1/authentication/refresh", `{"refresh_token":"unknown"}`)
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should require a refresh token", func(t *testing.T) {
+		rr := post(t, "/v1/authentication/refresh", `{}`)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should revoke the session on logout", func(t *testing.T) {
+		rr := post(t, "/v1/authentication/logout", `{"refresh_token":"`+store.MockRefreshToken+`"}`)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("should not log out with an unknown refresh token", func(t *testing.T) {
+		rr := post(t, "/v1/authentication/logout", `{"refresh_token":"unknown"}`)
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should drop the revoked session from the cache", func(t *testing.T) {
+		app := newTestApplication(t, config{redisCfg: redisConfig{enabled: true}})
+		mux := app.mount()
+
+		mockSessionCache := app.cacheStorage.Sessions.(*cache.MockSessionStore)
+		mockSessionCache.On("Delete", "test-session").Return(nil)
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/logout", strings.NewReader(`{"refresh_token":"`+store.MockRefreshToken+`"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+
+		mockSessionCache.AssertCalled(t, "Delete", "test-session")
+	})
+
+	t.Run("should drop a session revoked for token reuse from the cache", func(t *testing.T) {
+		app := newTestApplication(t, config{redisCfg: redisConfig{enabled: true}})
+		mux := app.mount()
+
+		mockSessionCache := app.cacheStorage.Sessions.(*cache.MockSessionStore)
+		mockSessionCache.On("Delete", "test-session").Return(nil)
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/refresh", strings.NewReader(`{"refresh_token":"`+store.MockReusedRefreshToken+`"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+
+		mockSessionCache.AssertCalled(t, "Delete", "test-session")
+	})
+}
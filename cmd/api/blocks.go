@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	errInvalidUserID = errors.New("invalid user ID")
+	errSelfTarget    = errors.New("cannot block or mute yourself")
+)
+
+// BlockUser godoc
+//
+//	@Summary		Blocks a user
+//	@Description	Blocks a user by ID. Follows between the two users are removed, the blocked user can no longer follow the blocker, see their posts or comment on them
+//	@Tags			users
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"User blocked"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error	"User not found"
+//	@Failure		409		{object}	error	"User already blocked"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/block [put]
+func (app *application) blockUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	blockedID, ok := app.readTargetUserID(w, r, user)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Blocks.Block(ctx, user.ID, blockedID); err != nil {
+		switch err {
+		case store.ErrConflict:
+			app.conflictResponse(w, r, err)
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.forgetFollowCounts(ctx, user.ID, blockedID)
+	app.forgetSuggestions(ctx, user.ID, blockedID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnblockUser godoc
+//
+//	@Summary		Unblocks a user
+//	@Description	Unblocks a user by ID. Follows removed by the block are not restored
+//	@Tags			users
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"User unblocked"
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/block [delete]
+func (app *application) unblockUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	blockedID, ok := app.readTargetUserID(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := app.store.Blocks.Unblock(r.Context(), user.ID, blockedID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MuteUser godoc
+//
+//	@Summary		Mutes a user
+//	@Description	Mutes a user by ID, hiding their posts and comments from the authenticated user. The muted user is not notified
+//	@Tags			users
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"User muted"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error	"User not found"
+//	@Failure		409		{object}	error	"User already muted"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/mute [put]
+func (app *application) muteUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	mutedID, ok := app.readTargetUserID(w, r, user)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Mutes.Mute(ctx, user.ID, mutedID); err != nil {
+		switch err {
+		case store.ErrConflict:
+			app.conflictResponse(w, r, err)
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.forgetSuggestions(ctx, user.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnmuteUser godoc
+//
+//	@Summary		Unmutes a user
+//	@Tags			users
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"User unmuted"
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/mute [delete]
+func (app *application) unmuteUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	mutedID, ok := app.readTargetUserID(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := app.store.Mutes.Unmute(r.Context(), user.ID, mutedID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readTargetUserID parses the userID URL parameter, rejecting the
+// authenticated user's own ID.
+func (app *application) readTargetUserID(w http.ResponseWriter, r *http.Request, user *store.User) (int64, bool) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil || userID < 1 {
+		app.badRequestResponse(w, r, errInvalidUserID)
+		return 0, false
+	}
+
+	if userID == user.ID {
+		app.badRequestResponse(w, r, errSelfTarget)
+		return 0, false
+	}
+
+	return userID, true
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestBlockAndMute(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(t *testing.T, method, path string) int {
+		t.Helper()
+
+		req, err := http.NewRequest(method, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, mux).Code
+	}
+
+	for _, path := range []string{"/v1/users/2/block", "/v1/users/2/mute"} {
+		t.Run("should add and remove "+path, func(t *testing.T) {
+			checkResponseCode(t, http.StatusNoContent, do(t, http.MethodPut, path))
+			checkResponseCode(t, http.StatusNoContent, do(t, http.MethodDelete, path))
+		})
+	}
+
+	t.Run("should not allow targeting yourself", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, do(t, http.MethodPut, "/v1/users/1/block"))
+		checkResponseCode(t, http.StatusBadRequest, do(t, http.MethodPut, "/v1/users/1/mute"))
+	})
+
+	t.Run("should reject an invalid user ID", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, do(t, http.MethodPut, "/v1/users/0/block"))
+	})
+}
@@ -1,19 +1,41 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/go-chi/chi/v5"
 )
 
+type commentKey string
+
+const commentCtx commentKey = "comment"
+
+var (
+	errUnknownParent = errors.New("parent comment not found on this post")
+	errMaxDepth      = errors.New("replies cannot be nested any deeper")
+)
+
+type CommentList struct {
+	Comments []store.Comment `json:"comments"`
+	// NextCursor is passed as the cursor parameter to fetch the next page,
+	// it is empty on the last page
+	NextCursor string `json:"next_cursor"`
+}
+
 // CreateCommentPayload represents the payload for creating a new comment
 // @Description Comment creation payload
 type CreateCommentPayload struct {
 	Content string `json:"content" validate:"required,max=300" example:"This is a comment"`
+	// ParentID makes the comment a reply to another comment on the post
+	ParentID *int64 `json:"parent_id" validate:"omitempty,min=1" example:"1"`
 }
 
 // @Summary		Create a comment
-// @Description	Creates a new comment on a post
+// @Description	Creates a new comment on a post, or a reply to a comment when parent_id is set
 // @Tags			posts,comments
 // @Accept			json
 // @Produce		json
@@ -22,12 +44,13 @@ type CreateCommentPayload struct {
 // @Param			comment	body		CreateCommentPayload	true	"Comment content"
 // @Success		201		{object}	store.Comment
 // @Failure		400		{object}	nil
+// @Failure		403		{object}	nil	"Blocked by the author of the post or of the parent comment"
 // @Failure		404		{object}	nil
 // @Failure		500		{object}	nil
 // @Router			/posts/{postID}/comments [post]
 func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromContext(r)
-	userId := 2
+	user := getUserFromContext(r)
 	ctx := r.Context()
 
 	var payload CreateCommentPayload
@@ -42,14 +65,47 @@ func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if !app.checkPostVisibility(w, r, post) {
+		return
+	}
+
+	if payload.ParentID != nil {
+		parent, err := app.store.Comments.GetByID(ctx, *payload.ParentID)
+		if err != nil {
+			switch err {
+			case store.ErrNotFound:
+				app.badRequestResponse(w, r, errUnknownParent)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		if parent.PostID != post.ID {
+			app.badRequestResponse(w, r, errUnknownParent)
+			return
+		}
+
+		if parent.Depth >= app.config.comments.maxDepth {
+			app.badRequestResponse(w, r, errMaxDepth)
+			return
+		}
+	}
+
 	comment := &store.Comment{
-		PostID:  post.ID,
-		UserID:  int64(userId),
-		Content: payload.Content,
+		PostID:   post.ID,
+		UserID:   user.ID,
+		ParentID: payload.ParentID,
+		Content:  payload.Content,
 	}
 
 	if err := app.store.Comments.Create(ctx, comment); err != nil {
-		app.internalServerError(w, r, err)
+		switch err {
+		case store.ErrBlocked:
+			app.forbiddenResponse(w, r)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
@@ -58,3 +114,216 @@ func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 }
+
+// @Summary		Lists comments
+// @Description	Lists the top-level comments of a post, most recent first. Replies are loaded with /comments/{commentID}/replies
+// @Tags			posts,comments
+// @Produce		json
+// @Security		ApiKeyAuth
+// @Param			postID	path		int		true	"Post ID"
+// @Param			limit	query		int		false	"Number of comments to return (default 20)"	minimum(1)	maximum(100)
+// @Param			cursor	query		string	false	"Cursor returned with the previous page"
+// @Success		200		{object}	CommentList
+// @Failure		400		{object}	nil
+// @Failure		404		{object}	nil
+// @Failure		500		{object}	nil
+// @Router			/posts/{postID}/comments [get]
+func (app *application) listCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromContext(r)
+
+	if !app.checkPostVisibility(w, r, post) {
+		return
+	}
+
+	cq, ok := app.readCursorQuery(w, r)
+	if !ok {
+		return
+	}
+
+	comments, next, err := app.store.Comments.GetByPostID(r.Context(), post.ID, getUserFromContext(r).ID, cq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, CommentList{Comments: comments, NextCursor: next}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// @Summary		Lists replies
+// @Description	Lists the direct replies to a comment, oldest first
+// @Tags			comments
+// @Produce		json
+// @Security		ApiKeyAuth
+// @Param			commentID	path		int		true	"Comment ID"
+// @Param			limit		query		int		false	"Number of comments to return (default 20)"	minimum(1)	maximum(100)
+// @Param			cursor		query		string	false	"Cursor returned with the previous page"
+// @Success		200			{object}	CommentList
+// @Failure		400			{object}	nil
+// @Failure		404			{object}	nil
+// @Failure		500			{object}	nil
+// @Router			/comments/{commentID}/replies [get]
+func (app *application) listRepliesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	cq, ok := app.readCursorQuery(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	comment, err := app.store.Comments.GetByID(ctx, id)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	post, err := app.store.Posts.GetByID(ctx, comment.PostID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if !app.checkPostVisibility(w, r, post) {
+		return
+	}
+
+	replies, next, err := app.store.Comments.GetReplies(ctx, comment.ID, getUserFromContext(r).ID, cq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, CommentList{Comments: replies, NextCursor: next}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type UpdateCommentPayload struct {
+	Content string `json:"content" validate:"required,max=300" example:"This is an edited comment"`
+}
+
+// @Summary		Update a comment
+// @Description	Updates the content of a comment and marks it as edited. Only the author and moderators can edit it
+// @Tags			comments
+// @Accept			json
+// @Produce		json
+// @Security		ApiKeyAuth
+// @Param			postID		path		int						true	"Post ID"
+// @Param			commentID	path		int						true	"Comment ID"
+// @Param			comment		body		UpdateCommentPayload	true	"Comment content"
+// @Success		200			{object}	store.Comment
+// @Failure		400			{object}	nil
+// @Failure		403			{object}	nil
+// @Failure		404			{object}	nil
+// @Failure		500			{object}	nil
+// @Router			/posts/{postID}/comments/{commentID} [patch]
+func (app *application) updateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromContext(r)
+
+	var payload UpdateCommentPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	comment.Content = payload.Content
+
+	if err := app.store.Comments.Update(r.Context(), comment); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, comment); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// @Summary		Delete a comment
+// @Description	Deletes a comment along with its replies. Only the author and admins can delete it
+// @Tags			comments
+// @Security		ApiKeyAuth
+// @Param			postID		path		int		true	"Post ID"
+// @Param			commentID	path		int		true	"Comment ID"
+// @Success		204			{string}	string	"Comment deleted"
+// @Failure		403			{object}	nil
+// @Failure		404			{object}	nil
+// @Failure		500			{object}	nil
+// @Router			/posts/{postID}/comments/{commentID} [delete]
+func (app *application) deleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.store.Comments.Delete(r.Context(), getCommentFromContext(r).ID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// commentsContextMiddleware loads the comment of the URL, which must belong to
+// the post loaded by postsContextMiddleware.
+func (app *application) commentsContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		comment, err := app.store.Comments.GetByID(ctx, id)
+		if err != nil {
+			switch err {
+			case store.ErrNotFound:
+				app.notFoundResponse(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		if comment.PostID != getPostFromContext(r).ID {
+			app.notFoundResponse(w, r, store.ErrNotFound)
+			return
+		}
+
+		ctx = context.WithValue(ctx, commentCtx, comment)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getCommentFromContext(r *http.Request) *store.Comment {
+	return r.Context().Value(commentCtx).(*store.Comment)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+)
+
+func TestCommentThreads(t *testing.T) {
+	app := newTestApplication(t, config{
+		comments: commentConfig{maxDepth: 1},
+	})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(t *testing.T, method, path string, body io.Reader) int {
+		t.Helper()
+
+		req, err := http.NewRequest(method, path, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should list comments and replies", func(t *testing.T) {
+		cursor := store.Cursor{CreatedAt: "2024-01-02T15:04:05Z", ID: 42}.Encode()
+		checkResponseCode(t, http.StatusOK, do(t, http.MethodGet, "/v1/posts/1/comments?limit=10", nil))
+		checkResponseCode(t, http.StatusOK, do(t, http.MethodGet, "/v1/posts/1/comments?cursor="+cursor, nil))
+		checkResponseCode(t, http.StatusOK, do(t, http.MethodGet, "/v1/comments/3/replies", nil))
+	})
+
+	t.Run("should reject an invalid cursor", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, do(t, http.MethodGet, "/v1/comments/3/replies?cursor=not-a-cursor", nil))
+	})
+
+	t.Run("should reply to a comment", func(t *testing.T) {
+		body := `{"content":"Agreed","parent_id":3}`
+		checkResponseCode(t, http.StatusCreated, do(t, http.MethodPost, "/v1/posts/1/comments", strings.NewReader(body)))
+	})
+
+	t.Run("should not reply to a comment of another post", func(t *testing.T) {
+		body := `{"content":"Agreed","parent_id":3}`
+		checkResponseCode(t, http.StatusBadRequest, do(t, http.MethodPost, "/v1/posts/2/comments", strings.NewReader(body)))
+	})
+
+	t.Run("should not nest replies deeper than configured", func(t *testing.T) {
+		app.config.comments.maxDepth = 0
+
+		body := `{"content":"Agreed","parent_id":3}`
+		checkResponseCode(t, http.StatusBadRequest, do(t, http.MethodPost, "/v1/posts/1/comments", strings.NewReader(body)))
+	})
+}
+
+func TestEditAndDeleteComments(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(t *testing.T, method, path string, body io.Reader) int {
+		t.Helper()
+
+		req, err := http.NewRequest(method, path, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should let the author edit and delete their comment", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, do(t, http.MethodPatch, "/v1/posts/1/comments/3", strings.NewReader(`{"content":"Edited"}`)))
+		checkResponseCode(t, http.StatusNoContent, do(t, http.MethodDelete, "/v1/posts/1/comments/3", nil))
+	})
+
+	t.Run("should reject empty content", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, do(t, http.MethodPatch, "/v1/posts/1/comments/3", strings.NewReader(`{"content":""}`)))
+	})
+
+	t.Run("should not find a comment of another post", func(t *testing.T) {
+		checkResponseCode(t, http.StatusNotFound, do(t, http.MethodDelete, "/v1/posts/2/comments/3", nil))
+	})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+)
+
+// purgeBatchSize bounds the number of accounts purged per sweep
+const purgeBatchSize = 100
+
+type AccountDeletion struct {
+	DeleteAfter time.Time `json:"delete_after"`
+}
+
+// DeleteAccount godoc
+//
+//	@Summary		Deletes the account
+//	@Description	Deactivates the authenticated user's account right away and logs it out everywhere. The account is permanently deleted after the grace period, logging in before then restores it
+//	@Tags			users
+//	@Produce		json
+//	@Success		202	{object}	AccountDeletion
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me [delete]
+func (app *application) deleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	deleteAfter := time.Now().Add(app.config.deletion.gracePeriod).UTC().Truncate(time.Second)
+
+	if err := app.store.Users.Deactivate(ctx, user.ID, deleteAfter); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.forgetUserSessions(ctx, user.ID)
+
+	if app.config.redisCfg.enabled {
+		app.cacheStorage.Users.Delete(ctx, user.ID)
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, AccountDeletion{DeleteAfter: deleteAfter}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getLoginUser returns the user logging in, also while the account is
+// deactivated and logging in still restores it.
+func (app *application) getLoginUser(ctx context.Context, userID int64) (*store.User, error) {
+	user, err := app.store.Users.GetByID(ctx, userID)
+	if err == store.ErrNotFound {
+		return app.store.Users.GetDeactivatedByID(ctx, userID)
+	}
+
+	return user, err
+}
+
+// reactivateUser restores the account of a user who logged in during the
+// deletion grace period. It must only be called once the login is complete.
+func (app *application) reactivateUser(w http.ResponseWriter, r *http.Request, user *store.User) bool {
+	if user.DeactivatedAt == nil {
+		return true
+	}
+
+	if err := app.store.Users.Reactivate(r.Context(), user.ID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return false
+	}
+
+	return true
+}
+
+// purgeDeletedUsers periodically deletes accounts whose deletion grace period
+// is over, until ctx is cancelled.
+func (app *application) purgeDeletedUsers(ctx context.Context) {
+	ticker := time.NewTicker(app.config.deletion.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := app.purgeDueUsers(ctx)
+			if err != nil {
+				app.logger.Errorw("error purging deleted users", "error", err)
+				continue
+			}
+
+			if purged > 0 {
+				app.logger.Infow("purged deleted users", "count", purged)
+			}
+		}
+	}
+}
+
+func (app *application) purgeDueUsers(ctx context.Context) (int, error) {
+	ids, err := app.store.Users.GetDueForDeletion(ctx, time.Now(), purgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		uploads, err := app.store.Users.Purge(ctx, id, app.config.deletion.anonymize)
+		if err != nil {
+			// the user logged in again or the purge failed, either way the
+			// next sweep picks it up if it is still due
+			app.logger.Errorw("error purging user", "user_id", id, "error", err)
+			continue
+		}
+
+		for i := range uploads {
+			app.deleteBlobs(ctx, &uploads[i])
+		}
+
+		purged++
+	}
+
+	return purged, nil
+}
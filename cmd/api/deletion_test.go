@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+)
+
+func TestDeleteAccount(t *testing.T) {
+	app := newTestApplication(t, config{
+		deletion: deletionConfig{gracePeriod: time.Hour * 24},
+	})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should schedule the deletion", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodDelete, "/v1/users/me", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusAccepted, rr.Code)
+
+		var res struct {
+			Data AccountDeletion `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+
+		if until := time.Until(res.Data.DeleteAfter); until < time.Hour*23 || until > time.Hour*24 {
+			t.Errorf("expected the deletion in a day, got %s", res.Data.DeleteAfter)
+		}
+	})
+
+	t.Run("should purge nothing when no account is due", func(t *testing.T) {
+		purged, err := app.purgeDueUsers(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if purged != 0 {
+			t.Errorf("expected no purged users, got %d", purged)
+		}
+	})
+}
+
+func TestReactivateAccount(t *testing.T) {
+	login := func(t *testing.T, users *deactivatedUserStore) int {
+		t.Helper()
+
+		app := newTestApplication(t, config{})
+		app.store.Users = users
+
+		body := bytes.NewBufferString(`{"email":"deleted@example.com","password":"password"}`)
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/token", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return executeRequest(req, app.mount()).Code
+	}
+
+	t.Run("should restore the account on login", func(t *testing.T) {
+		users := &deactivatedUserStore{}
+
+		checkResponseCode(t, http.StatusCreated, login(t, users))
+
+		if !users.reactivated {
+			t.Error("expected the account to be restored")
+		}
+	})
+
+	t.Run("should not restore the account before the second factor", func(t *testing.T) {
+		users := &deactivatedUserStore{totpEnabled: true}
+
+		checkResponseCode(t, http.StatusAccepted, login(t, users))
+
+		if users.reactivated {
+			t.Error("expected the account to stay deactivated")
+		}
+	})
+}
+
+// deactivatedUserStore knows a single account, awaiting deletion, whose
+// password is "password".
+type deactivatedUserStore struct {
+	store.MockUserStore
+	totpEnabled bool
+	reactivated bool
+}
+
+func (m *deactivatedUserStore) GetByID(ctx context.Context, id int64) (*store.User, error) {
+	return nil, store.ErrNotFound
+}
+
+func (m *deactivatedUserStore) GetDeactivatedByEmail(ctx context.Context, email string) (*store.User, error) {
+	return m.deactivatedUser(email)
+}
+
+func (m *deactivatedUserStore) GetDeactivatedByID(ctx context.Context, id int64) (*store.User, error) {
+	return m.deactivatedUser("deleted@example.com")
+}
+
+func (m *deactivatedUserStore) deactivatedUser(email string) (*store.User, error) {
+	deactivatedAt := "2024-01-02T15:04:05Z"
+	user := &store.User{ID: 1, Email: email, TOTPEnabled: m.totpEnabled, DeactivatedAt: &deactivatedAt}
+
+	if err := user.Password.Set("password"); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (m *deactivatedUserStore) Reactivate(ctx context.Context, userID int64) error {
+	m.reactivated = true
+	return nil
+}
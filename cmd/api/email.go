@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ana-tonic/gopher-social/internal/mailer"
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type ChangeEmailPayload struct {
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,min=3,max=72"`
+}
+
+// ChangeEmailHandler godoc
+//
+//	@Summary		Requests an email change
+//	@Description	Sends a confirmation link to the new address. The email is only changed once the link is confirmed
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		ChangeEmailPayload	true	"New email and current password"
+//	@Success		202		{string}	string				"Confirmation sent"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/email [patch]
+func (app *application) changeEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ChangeEmailPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	// the cached user does not carry the password hash
+	user, err := app.store.Users.GetByID(ctx, getUserFromContext(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := user.Password.Compare(payload.Password); err != nil {
+		app.unauthorizedErrorResponse(w, r, err)
+		return
+	}
+
+	if strings.EqualFold(user.Email, payload.Email) {
+		app.badRequestResponse(w, r, errors.New("new email is the same as the current one"))
+		return
+	}
+
+	plainToken := uuid.New().String()
+
+	if err := app.store.Users.CreateEmailChange(ctx, user.ID, payload.Email, plainToken, app.config.mail.exp); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	isProdEnv := app.config.env == "production"
+	vars := struct {
+		Username        string
+		ConfirmationURL string
+	}{
+		Username:        user.Username,
+		ConfirmationURL: fmt.Sprintf("%s/confirm-email/%s", app.config.frontendURL, plainToken),
+	}
+
+	status, err := app.mailer.Send(mailer.EmailChangeTemplate, user.Username, payload.Email, vars, !isProdEnv)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.logger.Infow("Email sent with status code", "status", status)
+
+	if err := app.jsonResponse(w, http.StatusAccepted, "a confirmation link has been sent to the new email"); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ConfirmEmailHandler godoc
+//
+//	@Summary		Confirms an email change
+//	@Description	Switches the account to the new email and notifies the previous address
+//	@Tags			users
+//	@Param			token	path		string	true	"Email change token"
+//	@Success		204		{string}	string	"Email changed"
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error	"Email already taken"
+//	@Failure		500		{object}	error
+//	@Router			/users/email/confirm/{token} [put]
+func (app *application) confirmEmailHandler(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	user, oldEmail, err := app.store.Users.ConfirmEmailChange(r.Context(), token)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrDuplicateEmail:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if app.config.redisCfg.enabled {
+		app.cacheStorage.Users.Delete(r.Context(), user.ID)
+	}
+
+	go app.sendEmailChangedAlert(user, oldEmail)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) sendEmailChangedAlert(user *store.User, oldEmail string) {
+	isProdEnv := app.config.env == "production"
+	vars := struct {
+		Username string
+		NewEmail string
+	}{
+		Username: user.Username,
+		NewEmail: user.Email,
+	}
+
+	status, err := app.mailer.Send(mailer.EmailChangedTemplate, user.Username, oldEmail, vars, !isProdEnv)
+	if err != nil {
+		app.logger.Errorw("error sending email changed alert", "error", err)
+		return
+	}
+
+	app.logger.Infow("Email sent with status code", "status", status)
+}
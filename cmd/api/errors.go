@@ -53,3 +53,29 @@ func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http
 
 	writeJSONError(w, http.StatusTooManyRequests, "rate limit exceeded, retry after "+retryAfter)
 }
+
+func (app *application) accountLockedResponse(w http.ResponseWriter, r *http.Request, retryAfter string) {
+	app.logger.Warnw("account locked", "method", r.Method, "path", r.URL.Path)
+
+	w.Header().Set("Retry-After", retryAfter)
+
+	writeJSONError(w, http.StatusLocked, "account temporarily locked, retry after "+retryAfter)
+}
+
+func (app *application) ipLockedResponse(w http.ResponseWriter, r *http.Request, retryAfter string) {
+	app.logger.Warnw("client ip locked", "method", r.Method, "path", r.URL.Path)
+
+	w.Header().Set("Retry-After", retryAfter)
+
+	writeJSONError(w, http.StatusTooManyRequests, "too many failed logins from this address, retry after "+retryAfter)
+}
+
+func (app *application) payloadTooLargeResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnw("payload too large", "method", r.Method, "path", r.URL.Path, "error", err.Error())
+	writeJSONError(w, http.StatusRequestEntityTooLarge, err.Error())
+}
+
+func (app *application) unsupportedMediaTypeResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnw("unsupported media type", "method", r.Method, "path", r.URL.Path, "error", err.Error())
+	writeJSONError(w, http.StatusUnsupportedMediaType, err.Error())
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ana-tonic/gopher-social/internal/export"
+	"github.com/ana-tonic/gopher-social/internal/mailer"
+	"github.com/ana-tonic/gopher-social/internal/media"
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// exportBuildTimeout bounds building an archive. A user can request another
+// export once the previous one is done or this long has passed.
+const exportBuildTimeout = time.Hour
+
+// RequestExport godoc
+//
+//	@Summary		Requests a data export
+//	@Description	Starts building a ZIP archive with the authenticated user's profile, posts, comments, followers and following. A download link is emailed when it is ready
+//	@Tags			users
+//	@Produce		json
+//	@Success		202	{object}	store.DataExport
+//	@Failure		403	{object}	error
+//	@Failure		409	{object}	error	"An export is already being built"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/export [post]
+func (app *application) requestExportHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	e := &store.DataExport{UserID: user.ID}
+
+	if err := app.store.Exports.Create(r.Context(), e, time.Now().Add(-exportBuildTimeout)); err != nil {
+		switch err {
+		case store.ErrConflict:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	go app.buildExport(user.ID, e)
+
+	if err := app.jsonResponse(w, http.StatusAccepted, e); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DownloadExport godoc
+//
+//	@Summary		Downloads a data export
+//	@Description	Downloads the archive with the token from the export email
+//	@Tags			users
+//	@Produce		application/zip
+//	@Param			token	path		string	true	"Download token"
+//	@Success		200		{file}		file
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/exports/{token} [get]
+func (app *application) downloadExportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	e, err := app.store.Exports.GetByToken(ctx, chi.URLParam(r, "token"))
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	blob, err := app.blobStore.Get(ctx, e.Key)
+	if err != nil {
+		switch err {
+		case media.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="gophersocial-export.zip"`)
+	w.Header().Set("Cache-Control", "no-store")
+
+	if _, err := io.Copy(w, blob); err != nil {
+		app.logger.Errorw("error streaming data export", "export_id", e.ID, "error", err)
+	}
+}
+
+// buildExport writes the user's archive to the blob store and emails them the
+// download link. A failed export is deleted so the user can ask again.
+func (app *application) buildExport(userID int64, e *store.DataExport) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportBuildTimeout)
+	defer cancel()
+
+	user, err := app.store.Users.GetByID(ctx, userID)
+	if err != nil {
+		app.exportFailed(ctx, e, err)
+		return
+	}
+
+	archive, err := app.collectExport(ctx, user)
+	if err != nil {
+		app.exportFailed(ctx, e, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := export.Write(&buf, archive); err != nil {
+		app.exportFailed(ctx, e, err)
+		return
+	}
+
+	e.Key = fmt.Sprintf("exports/%d/%s.zip", user.ID, uuid.New().String())
+
+	if err := app.blobStore.Put(ctx, e.Key, &buf, int64(buf.Len()), "application/zip"); err != nil {
+		app.exportFailed(ctx, e, err)
+		return
+	}
+
+	plainToken := uuid.New().String()
+
+	if err := app.store.Exports.Complete(ctx, e, plainToken, app.config.export.exp); err != nil {
+		if err := app.blobStore.Delete(ctx, e.Key); err != nil {
+			app.logger.Errorw("error deleting blob", "key", e.Key, "error", err)
+		}
+		app.exportFailed(ctx, e, err)
+		return
+	}
+
+	isProdEnv := app.config.env == "production"
+	vars := struct {
+		Username    string
+		DownloadURL string
+		ExpiresIn   string
+	}{
+		Username:    user.Username,
+		DownloadURL: fmt.Sprintf("%s/v1/exports/%s", app.config.apiURL, plainToken),
+		ExpiresIn:   app.config.export.exp.String(),
+	}
+
+	status, err := app.mailer.Send(mailer.DataExportTemplate, user.Username, user.Email, vars, !isProdEnv)
+	if err != nil {
+		app.logger.Errorw("error sending data export email", "export_id", e.ID, "error", err)
+		return
+	}
+
+	app.logger.Infow("Email sent with status code", "status", status)
+}
+
+func (app *application) collectExport(ctx context.Context, user *store.User) (*export.Archive, error) {
+	archive := &export.Archive{
+		Profile: export.Profile{
+			ID:                 user.ID,
+			Username:           user.Username,
+			Email:              user.Email,
+			CreatedAt:          user.CreatedAt,
+			DisplayName:        user.DisplayName,
+			Bio:                user.Bio,
+			Website:            user.Website,
+			Location:           user.Location,
+			Birthday:           user.Birthday,
+			BirthdayVisibility: user.BirthdayVisibility,
+			IsPrivate:          user.IsPrivate,
+		},
+		Posts:     []export.Post{},
+		Comments:  []export.Comment{},
+		CreatedAt: time.Now().UTC(),
+	}
+
+	posts, err := app.store.Exports.GetPosts(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range posts {
+		archive.Posts = append(archive.Posts, export.Post{
+			ID:        p.ID,
+			Title:     p.Title,
+			Content:   p.Content,
+			Tags:      p.Tags,
+			CreatedAt: p.CreatedAt,
+			UpdatedAt: p.UpdatedAt,
+		})
+	}
+
+	comments, err := app.store.Exports.GetComments(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range comments {
+		archive.Comments = append(archive.Comments, export.Comment{
+			ID:        c.ID,
+			PostID:    c.PostID,
+			Content:   c.Content,
+			CreatedAt: c.CreatedAt,
+		})
+	}
+
+	if archive.Followers, err = app.collectAccounts(ctx, user.ID, app.store.Followers.GetFollowers); err != nil {
+		return nil, err
+	}
+
+	if archive.Following, err = app.collectAccounts(ctx, user.ID, app.store.Followers.GetFollowing); err != nil {
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+// collectAccounts reads every page of a followers or following list.
+func (app *application) collectAccounts(ctx context.Context, userID int64, list followLister) ([]export.Account, error) {
+	accounts := []export.Account{}
+	cq := store.CursorQuery{Limit: 100}
+
+	for {
+		users, next, err := list(ctx, userID, userID, cq)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, u := range users {
+			accounts = append(accounts, export.Account{
+				ID:         u.ID,
+				Username:   u.Username,
+				FollowedAt: u.FollowedAt,
+			})
+		}
+
+		if next == "" {
+			return accounts, nil
+		}
+
+		if cq.After, err = store.DecodeCursor(next); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (app *application) exportFailed(ctx context.Context, e *store.DataExport, err error) {
+	app.logger.Errorw("error building data export", "export_id", e.ID, "error", err)
+
+	if err := app.store.Exports.Delete(ctx, e.ID); err != nil {
+		app.logger.Errorw("error deleting failed data export", "export_id", e.ID, "error", err)
+	}
+}
+
+// sweepExpiredExports periodically deletes expired exports and their
+// archives, until ctx is cancelled.
+func (app *application) sweepExpiredExports(ctx context.Context) {
+	ticker := time.NewTicker(app.config.export.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			keys, err := app.store.Exports.DeleteExpired(ctx, time.Now())
+			if err != nil {
+				app.logger.Errorw("error sweeping expired data exports", "error", err)
+				continue
+			}
+
+			for _, key := range keys {
+				if err := app.blobStore.Delete(ctx, key); err != nil {
+					app.logger.Errorw("error deleting blob", "key", key, "error", err)
+				}
+			}
+
+			if len(keys) > 0 {
+				app.logger.Infow("swept expired data exports", "count", len(keys))
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+)
+
+type sentMail struct {
+	template string
+	data     any
+}
+
+type testMailer struct {
+	sent chan sentMail
+}
+
+func (m *testMailer) Send(templateFile, username, email string, data any, isSandbox bool) (int, error) {
+	m.sent <- sentMail{template: templateFile, data: data}
+	return http.StatusOK, nil
+}
+
+func waitForMail(t *testing.T, m *testMailer) sentMail {
+	t.Helper()
+
+	select {
+	case mail := <-m.sent:
+		return mail
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the export email")
+		return sentMail{}
+	}
+}
+
+func TestDataExport(t *testing.T) {
+	app := newTestApplication(t, config{
+		apiURL: "http://localhost:8080",
+		export: exportConfig{exp: time.Hour},
+	})
+	mailer := &testMailer{sent: make(chan sentMail, 1)}
+	app.mailer = mailer
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should accept the request and email a download link", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/v1/users/me/export", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusAccepted, rr.Code)
+
+		mail := waitForMail(t, mailer)
+
+		url := mail.data.(struct {
+			Username    string
+			DownloadURL string
+			ExpiresIn   string
+		}).DownloadURL
+		if !strings.HasPrefix(url, "http://localhost:8080/v1/exports/") {
+			t.Errorf("unexpected download URL %q", url)
+		}
+	})
+
+	t.Run("should store the archive", func(t *testing.T) {
+		e := &store.DataExport{ID: 1, UserID: 1}
+		app.buildExport(e.UserID, e)
+		waitForMail(t, mailer)
+
+		blob, err := app.blobStore.Get(context.Background(), e.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer blob.Close()
+
+		data, err := io.ReadAll(blob)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names := map[string]bool{}
+		for _, f := range zr.File {
+			names[f.Name] = true
+		}
+
+		for _, name := range []string{"index.html", "profile.json", "posts.json", "comments.json", "followers.json", "following.json"} {
+			if !names[name] {
+				t.Errorf("expected %s in the archive", name)
+			}
+		}
+	})
+
+	t.Run("should not find an unknown download token", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/exports/unknown", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should refuse a second export while one is being built", func(t *testing.T) {
+		app := newTestApplication(t, config{})
+		app.store.Exports = &pendingExportStore{}
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/users/me/export", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, app.mount())
+		checkResponseCode(t, http.StatusConflict, rr.Code)
+	})
+}
+
+// pendingExportStore has an export of every user being built.
+type pendingExportStore struct {
+	store.MockExportStore
+}
+
+func (m *pendingExportStore) Create(ctx context.Context, export *store.DataExport, pendingSince time.Time) error {
+	return store.ErrConflict
+}
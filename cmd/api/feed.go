@@ -2,12 +2,14 @@ package main
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/go-chi/chi/v5"
 )
 
 // @Summary		Fetches the user feed
-// @Description	Fetches the user feed
+// @Description	Fetches the authenticated user's posts and those of the users they follow
 // @Tags			feed
 // @Accept			json
 // @Produce		json
@@ -24,36 +26,106 @@ import (
 // @Security		ApiKeyAuth
 // @Router			/users/feed [get]
 func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Request) {
-	// TODO: Get authenticated user ID from context
-	// For now, we'll use a hardcoded ID for testing
-	userID := int64(1) // This should come from auth context
-
-	fq := store.PaginatedFeedQuery{
-		Limit:  20,
-		Offset: 0,
-		Sort:   "desc",
+	fq, ok := app.readFeedQuery(w, r)
+	if !ok {
+		return
 	}
 
-	fq, err := fq.Parse(r)
+	feed, err := app.store.Posts.GetUserFeed(r.Context(), getUserFromContext(r).ID, fq)
 	if err != nil {
-		app.badRequestResponse(w, r, err)
+		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := Validate.Struct(fq); err != nil {
-		app.badRequestResponse(w, r, err)
+	if err := app.jsonResponse(w, http.StatusOK, feed); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// @Summary		Fetches a user's posts
+// @Description	Fetches the posts of a user for their profile. Posts of private accounts are only shown to approved followers
+// @Tags			feed
+// @Accept			json
+// @Produce		json
+// @Param			userID	path		int			true	"User ID"
+// @Param			limit	query		int			false	"Number of posts to return (default 20)"	minimum(1)	maximum(20)
+// @Param			offset	query		int			false	"Offset for pagination (default 0)"			minimum(0)
+// @Param			sort	query		string		false	"Sort order (asc or desc, default desc)"	Enums(asc,desc)
+// @Param			search	query		string		false	"Search in title and content"
+// @Param			tags	query		[]string	false	"Filter by tags (comma separated)"
+// @Param			since	query		string		false	"Filter posts since date (format: 2006-01-02 15:04:05)"
+// @Param			until	query		string		false	"Filter posts until date (format: 2006-01-02 15:04:05)"
+// @Success		200		{object}	[]store.PostWithMetadata
+// @Failure		400		{object}	error
+// @Failure		404		{object}	error
+// @Failure		500		{object}	error
+// @Security		ApiKeyAuth
+// @Router			/users/{userID}/posts [get]
+func (app *application) getUserPostsHandler(w http.ResponseWriter, r *http.Request) {
+	authorID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil || authorID < 1 {
+		app.badRequestResponse(w, r, errInvalidUserID)
+		return
+	}
+
+	fq, ok := app.readFeedQuery(w, r)
+	if !ok {
 		return
 	}
 
+	viewer := getUserFromContext(r)
 	ctx := r.Context()
 
-	feed, err := app.store.Posts.GetUserFeed(ctx, userID, fq)
+	if _, err := app.getUser(ctx, authorID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// hidden posts are reported as not found, as with single posts
+	visible, err := app.store.Users.CanViewPosts(ctx, authorID, viewer.ID)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, feed); err != nil {
+	if !visible {
+		app.notFoundResponse(w, r, store.ErrNotFound)
+		return
+	}
+
+	posts, err := app.store.Posts.GetUserPosts(ctx, authorID, viewer.ID, fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, posts); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
+
+func (app *application) readFeedQuery(w http.ResponseWriter, r *http.Request) (store.PaginatedFeedQuery, bool) {
+	fq := store.PaginatedFeedQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   "desc",
+	}
+
+	fq, err := fq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return fq, false
+	}
+
+	if err := Validate.Struct(fq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return fq, false
+	}
+
+	return fq, true
+}
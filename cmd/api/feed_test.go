@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestFeed(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(t *testing.T, path string) int {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should fetch the authenticated user's feed", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, get(t, "/v1/users/feed?sort=asc&tags=go,sql"))
+	})
+
+	t.Run("should fetch a user's posts", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, get(t, "/v1/users/2/posts?limit=5&search=gopher"))
+	})
+
+	t.Run("should reject an invalid user ID", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, get(t, "/v1/users/0/posts"))
+	})
+
+	t.Run("should reject invalid filters", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, get(t, "/v1/users/2/posts?sort=sideways"))
+		checkResponseCode(t, http.StatusBadRequest, get(t, "/v1/users/feed?limit=21"))
+	})
+
+	t.Run("should require authentication", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/2/posts", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		checkResponseCode(t, http.StatusUnauthorized, executeRequest(req, mux).Code)
+	})
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type UserWithFollowStatus struct {
+	*store.User
+	FollowedByMe bool `json:"followed_by_me"`
+}
+
+type FollowList struct {
+	Users []store.FollowUser `json:"users"`
+	// NextCursor is passed as the cursor parameter to fetch the next page,
+	// it is empty on the last page
+	NextCursor string `json:"next_cursor"`
+}
+
+// GetFollowers godoc
+//
+//	@Summary		Lists followers
+//	@Description	Lists the users following a user, most recent first. Not found when the user's posts are hidden from the authenticated user
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Param			limit	query		int		false	"Number of users to return (default 20)"	minimum(1)	maximum(100)
+//	@Param			cursor	query		string	false	"Cursor returned with the previous page"
+//	@Success		200		{object}	FollowList
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/followers [get]
+func (app *application) getFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	app.listFollows(w, r, app.store.Followers.GetFollowers)
+}
+
+// GetFollowing godoc
+//
+//	@Summary		Lists followed users
+//	@Description	Lists the users a user follows, most recent first. Not found when the user's posts are hidden from the authenticated user
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Param			limit	query		int		false	"Number of users to return (default 20)"	minimum(1)	maximum(100)
+//	@Param			cursor	query		string	false	"Cursor returned with the previous page"
+//	@Success		200		{object}	FollowList
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/following [get]
+func (app *application) getFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	app.listFollows(w, r, app.store.Followers.GetFollowing)
+}
+
+// ListFollowRequests godoc
+//
+//	@Summary		Lists follow requests
+//	@Description	Lists the pending follow requests to the authenticated user, most recent first
+//	@Tags			users
+//	@Produce		json
+//	@Param			limit	query		int		false	"Number of users to return (default 20)"	minimum(1)	maximum(100)
+//	@Param			cursor	query		string	false	"Cursor returned with the previous page"
+//	@Success		200		{object}	FollowList
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/follow-requests [get]
+func (app *application) listFollowRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	cq, ok := app.readCursorQuery(w, r)
+	if !ok {
+		return
+	}
+
+	users, next, err := app.store.Followers.GetFollowRequests(r.Context(), getUserFromContext(r).ID, cq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, FollowList{Users: users, NextCursor: next}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ApproveFollowRequest godoc
+//
+//	@Summary		Approves a follow request
+//	@Tags			users
+//	@Param			userID	path		int		true	"ID of the user who sent the request"
+//	@Success		204		{string}	string	"Request approved"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error	"No pending request from the user"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/follow-requests/{userID} [put]
+func (app *application) approveFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	requesterID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil || requesterID < 1 {
+		app.badRequestResponse(w, r, errInvalidUserID)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Followers.ApproveFollowRequest(ctx, user.ID, requesterID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrConflict:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.forgetFollowCounts(ctx, requesterID, user.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RejectFollowRequest godoc
+//
+//	@Summary		Rejects a follow request
+//	@Tags			users
+//	@Param			userID	path		int		true	"ID of the user who sent the request"
+//	@Success		204		{string}	string	"Request rejected"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error	"No pending request from the user"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/follow-requests/{userID} [delete]
+func (app *application) rejectFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	requesterID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil || requesterID < 1 {
+		app.badRequestResponse(w, r, errInvalidUserID)
+		return
+	}
+
+	if err := app.store.Followers.RejectFollowRequest(r.Context(), getUserFromContext(r).ID, requesterID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type followLister func(ctx context.Context, userID, viewerID int64, cq store.CursorQuery) ([]store.FollowUser, string, error)
+
+func (app *application) listFollows(w http.ResponseWriter, r *http.Request, list followLister) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil || userID < 1 {
+		app.badRequestResponse(w, r, errInvalidUserID)
+		return
+	}
+
+	cq, ok := app.readCursorQuery(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	if _, err := app.getUser(ctx, userID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	viewer := getUserFromContext(r)
+
+	// the follows of a private or blocking user are hidden like their posts
+	visible, err := app.store.Users.CanViewPosts(ctx, userID, viewer.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if !visible {
+		app.notFoundResponse(w, r, store.ErrNotFound)
+		return
+	}
+
+	users, next, err := list(ctx, userID, viewer.ID, cq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, FollowList{Users: users, NextCursor: next}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) readCursorQuery(w http.ResponseWriter, r *http.Request) (store.CursorQuery, bool) {
+	cq := store.CursorQuery{
+		Limit: 20,
+	}
+
+	cq, err := cq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return cq, false
+	}
+
+	if err := Validate.Struct(cq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return cq, false
+	}
+
+	return cq, true
+}
+
+// forgetFollowCounts drops both users from the cache so their follower and
+// following counts are reloaded.
+func (app *application) forgetFollowCounts(ctx context.Context, followerID, userID int64) {
+	if !app.config.redisCfg.enabled {
+		return
+	}
+
+	app.cacheStorage.Users.Delete(ctx, followerID)
+	app.cacheStorage.Users.Delete(ctx, userID)
+}
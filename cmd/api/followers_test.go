@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+)
+
+func TestListFollowers(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(t *testing.T, path string) int {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should list followers and following", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, get(t, "/v1/users/1/followers"))
+		checkResponseCode(t, http.StatusOK, get(t, "/v1/users/1/following?limit=100"))
+	})
+
+	t.Run("should accept a cursor from a previous page", func(t *testing.T) {
+		cursor := store.Cursor{CreatedAt: "2024-01-02T15:04:05Z", ID: 42}.Encode()
+		checkResponseCode(t, http.StatusOK, get(t, "/v1/users/1/followers?cursor="+cursor))
+	})
+
+	t.Run("should reject an invalid cursor", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, get(t, "/v1/users/1/followers?cursor=not-a-cursor"))
+	})
+
+	t.Run("should reject an out of range limit", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, get(t, "/v1/users/1/following?limit=101"))
+	})
+
+	t.Run("should hide the follows of a hidden user", func(t *testing.T) {
+		app := newTestApplication(t, config{})
+		app.store.Users = &hiddenUserStore{}
+
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/2/followers", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		checkResponseCode(t, http.StatusNotFound, executeRequest(req, app.mount()).Code)
+	})
+
+	t.Run("should include the follow status on the user", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var res struct {
+			Data map[string]any `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, field := range []string{"followed_by_me", "follower_count", "following_count"} {
+			if _, ok := res.Data[field]; !ok {
+				t.Errorf("expected %q in the user payload", field)
+			}
+		}
+	})
+
+	t.Run("should list pending follow requests", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, get(t, "/v1/users/me/follow-requests"))
+	})
+
+	t.Run("should not find a missing follow request", func(t *testing.T) {
+		for _, method := range []string{http.MethodPut, http.MethodDelete} {
+			req, err := http.NewRequest(method, "/v1/users/me/follow-requests/2", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			checkResponseCode(t, http.StatusNotFound, executeRequest(req, mux).Code)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ana-tonic/gopher-social/internal/mailer"
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/google/uuid"
+)
+
+type ResendInvitationPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// ResendInvitationHandler godoc
+//
+//	@Summary		Resends the activation email
+//	@Description	Issues a new invitation token to an account that has not been activated, invalidating the previous one. The response is the same whether or not such an account exists
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		ResendInvitationPayload	true	"Account email"
+//	@Success		202		{string}	string					"Resend requested"
+//	@Failure		400		{object}	error
+//	@Failure		429		{object}	error
+//	@Router			/authentication/invitation/resend [post]
+func (app *application) resendInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ResendInvitationPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if allow, retryAfter := app.invitationLimiter.Allow(strings.ToLower(payload.Email)); !allow {
+		app.rateLimitExceededResponse(w, r, retryAfter.String())
+		return
+	}
+
+	go app.reissueInvitation(payload.Email)
+
+	msg := "if the account is awaiting activation, a new activation email has been sent"
+	if err := app.jsonResponse(w, http.StatusAccepted, msg); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) reissueInvitation(email string) {
+	plainToken := uuid.New().String()
+
+	hash := sha256.Sum256([]byte(plainToken))
+	hashToken := hex.EncodeToString(hash[:])
+
+	user, err := app.store.Users.ReissueInvitation(context.Background(), email, hashToken, app.config.mail.exp)
+	if err != nil {
+		if err != store.ErrNotFound {
+			app.logger.Errorw("error reissuing invitation", "error", err)
+		}
+		return
+	}
+
+	status, err := app.sendInvitationEmail(user, plainToken)
+	if err != nil {
+		app.logger.Errorw("error sending welcome email", "error", err)
+		return
+	}
+
+	app.logger.Infow("Email sent with status code", "status", status)
+}
+
+func (app *application) sendInvitationEmail(user *store.User, plainToken string) (int, error) {
+	activationURL := fmt.Sprintf("%s/confirm/%s", app.config.frontendURL, plainToken)
+
+	isProdEnv := app.config.env == "production"
+	vars := struct {
+		Username      string
+		ActivationURL string
+	}{
+		Username:      user.Username,
+		ActivationURL: activationURL,
+	}
+
+	return app.mailer.Send(mailer.UserWelcomeTemplate, user.Username, user.Email, vars, !isProdEnv)
+}
+
+// sweepUnactivatedUsers periodically deletes accounts that were never
+// activated within the retention period, until ctx is cancelled.
+func (app *application) sweepUnactivatedUsers(ctx context.Context) {
+	ticker := time.NewTicker(app.config.invitation.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			before := time.Now().Add(-app.config.invitation.retention)
+
+			deleted, err := app.store.Users.DeleteUnactivated(ctx, before)
+			if err != nil {
+				app.logger.Errorw("error sweeping unactivated users", "error", err)
+				continue
+			}
+
+			if deleted > 0 {
+				app.logger.Infow("swept unactivated users", "count", deleted)
+			}
+		}
+	}
+}
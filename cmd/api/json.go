@@ -11,6 +11,14 @@ var Validate *validator.Validate
 
 func init() {
 	Validate = validator.New(validator.WithRequiredStructEnabled())
+
+	// the names of the ghost user of purged accounts can't be taken
+	err := Validate.RegisterValidation("notreserved", func(fl validator.FieldLevel) bool {
+		return !isReservedUsername(fl.Field().String())
+	})
+	if err != nil {
+		panic(err)
+	}
 }
 
 func writeJSON(w http.ResponseWriter, status int, data any) error {
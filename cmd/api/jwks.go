@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ana-tonic/gopher-social/internal/auth"
+)
+
+// JWKSHandler godoc
+//
+//	@Summary		Publishes token verification keys
+//	@Description	Returns the JSON Web Key Set used to verify access tokens. Only available when tokens are signed with RSA or Ed25519 keys
+//	@Tags			authentication
+//	@Produce		json
+//	@Success		200	{object}	auth.JWKSet
+//	@Failure		404	{object}	error
+//	@Router			/.well-known/jwks.json [get]
+func (app *application) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	provider, ok := app.authenticator.(auth.KeySetProvider)
+	if !ok {
+		app.notFoundResponse(w, r, errors.New("authenticator does not publish keys"))
+		return
+	}
+
+	// JWKS consumers expect the bare key set, not the data envelope
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := writeJSON(w, http.StatusOK, provider.JWKS()); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
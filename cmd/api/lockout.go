@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ana-tonic/gopher-social/internal/mailer"
+	"github.com/ana-tonic/gopher-social/internal/ratelimiter"
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+func loginAccountKey(email string) string {
+	return "account-" + strings.ToLower(email)
+}
+
+func loginIPKey(r *http.Request) string {
+	return "ip-" + clientIP(r)
+}
+
+// loginLimits are the limits a login to the account with email counts
+// against.
+func (app *application) loginLimits(r *http.Request, email string) []ratelimiter.Limit {
+	cfg := app.loginGuard.Config()
+
+	return []ratelimiter.Limit{
+		{Key: loginAccountKey(email), MaxAttempts: cfg.AccountMaxAttempts},
+		{Key: loginIPKey(r), MaxAttempts: cfg.IPMaxAttempts},
+	}
+}
+
+// checkLogin counts a login attempt for the account and the client IP before
+// the credentials are checked. It writes a 423 response when the account is
+// locked, or a 429 when the client IP is locked or has to back off, and
+// returns false in both cases.
+func (app *application) checkLogin(w http.ResponseWriter, r *http.Request, email string) (ratelimiter.Verdict, bool) {
+	v, err := app.loginGuard.Attempt(r.Context(), app.loginLimits(r, email)...)
+	if err != nil {
+		switch err {
+		case ratelimiter.ErrContended:
+			app.rateLimitExceededResponse(w, r, time.Second.String())
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return v, false
+	}
+
+	if v.Wait <= 0 {
+		return v, true
+	}
+
+	retryAfter := v.Wait.Round(time.Second).String()
+	switch v.Locked {
+	case loginAccountKey(email):
+		app.accountLockedResponse(w, r, retryAfter)
+	case loginIPKey(r):
+		app.ipLockedResponse(w, r, retryAfter)
+	default:
+		app.rateLimitExceededResponse(w, r, retryAfter)
+	}
+
+	return v, false
+}
+
+// loginFailed warns the user when the failed attempt locked their account.
+// The attempt itself was already counted by checkLogin. user is nil when
+// the email is not registered.
+func (app *application) loginFailed(v ratelimiter.Verdict, email string, user *store.User) {
+	if user != nil && slices.Contains(v.Locking, loginAccountKey(email)) {
+		app.logger.Warnw("account locked after failed logins", "user_id", user.ID)
+		go app.sendAccountLockedEmail(user)
+	}
+}
+
+// loginSucceeded forgets the account's failures and takes back the attempt
+// counted for the client IP.
+func (app *application) loginSucceeded(ctx context.Context, r *http.Request, email string) {
+	if err := app.loginGuard.Reset(ctx, loginAccountKey(email)); err != nil {
+		app.logger.Errorw("error resetting failed logins", "error", err)
+	}
+
+	if err := app.loginGuard.Forgive(ctx, app.loginLimits(r, email)[1]); err != nil {
+		app.logger.Errorw("error resetting failed logins", "error", err)
+	}
+}
+
+// loginPending takes back the attempt of a correct password while the second
+// factor is still to be verified. Earlier failures only reset once it is.
+func (app *application) loginPending(ctx context.Context, r *http.Request, email string) {
+	for _, l := range app.loginLimits(r, email) {
+		if err := app.loginGuard.Forgive(ctx, l); err != nil {
+			app.logger.Errorw("error resetting failed logins", "error", err)
+		}
+	}
+}
+
+func (app *application) sendAccountLockedEmail(user *store.User) {
+	isProdEnv := app.config.env == "production"
+	vars := struct {
+		Username  string
+		LockedFor string
+		ResetURL  string
+	}{
+		Username:  user.Username,
+		LockedFor: app.loginGuard.Config().LockoutDuration.String(),
+		ResetURL:  fmt.Sprintf("%s/forgot-password", app.config.frontendURL),
+	}
+
+	status, err := app.mailer.Send(mailer.AccountLockedTemplate, user.Username, user.Email, vars, !isProdEnv)
+	if err != nil {
+		app.logger.Errorw("error sending account locked email", "error", err)
+		return
+	}
+
+	app.logger.Infow("Email sent with status code", "status", status)
+}
+
+// UnlockUser godoc
+//
+//	@Summary		Unlocks a user account
+//	@Description	Clears failed login attempts and lifts a lockout. Admin only
+//	@Tags			users
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{string}	string	"User unlocked"
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/unlock [put]
+func (app *application) unlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	user, err := app.store.Users.GetByID(ctx, userID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.loginGuard.Reset(ctx, loginAccountKey(user.Email)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.logger.Infow("account unlocked", "user_id", user.ID, "by", getUserFromContext(r).ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
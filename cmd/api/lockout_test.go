@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ana-tonic/gopher-social/internal/ratelimiter"
+)
+
+func TestLoginBackoff(t *testing.T) {
+	cfg := config{
+		loginGuard: ratelimiter.LoginConfig{
+			BaseDelay:          time.Minute,
+			MaxDelay:           time.Minute,
+			AccountMaxAttempts: 5,
+			IPMaxAttempts:      50,
+			LockoutDuration:    time.Minute,
+			Window:             time.Hour,
+		},
+	}
+
+	app := newTestApplication(t, cfg)
+	mux := app.mount()
+
+	login := func() int {
+		body := bytes.NewBufferString(`{"email":"nobody@example.com","password":"wrong-password"}`)
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/token", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return executeRequest(req, mux).Code
+	}
+
+	checkResponseCode(t, http.StatusUnauthorized, login())
+	checkResponseCode(t, http.StatusTooManyRequests, login())
+}
+
+func TestLoginLockout(t *testing.T) {
+	login := func(t *testing.T, mux http.Handler) int {
+		t.Helper()
+
+		body := bytes.NewBufferString(`{"email":"nobody@example.com","password":"wrong-password"}`)
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/token", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should report a locked account", func(t *testing.T) {
+		app := newTestApplication(t, config{
+			loginGuard: ratelimiter.LoginConfig{AccountMaxAttempts: 1, IPMaxAttempts: 50, LockoutDuration: time.Minute, Window: time.Hour},
+		})
+		mux := app.mount()
+
+		checkResponseCode(t, http.StatusUnauthorized, login(t, mux))
+		checkResponseCode(t, http.StatusLocked, login(t, mux))
+	})
+
+	t.Run("should not report a locked IP as a locked account", func(t *testing.T) {
+		app := newTestApplication(t, config{
+			loginGuard: ratelimiter.LoginConfig{AccountMaxAttempts: 5, IPMaxAttempts: 1, LockoutDuration: time.Minute, Window: time.Hour},
+		})
+		mux := app.mount()
+
+		checkResponseCode(t, http.StatusUnauthorized, login(t, mux))
+		checkResponseCode(t, http.StatusTooManyRequests, login(t, mux))
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/ana-tonic/gopher-social/internal/db"
 	"github.com/ana-tonic/gopher-social/internal/env"
 	"github.com/ana-tonic/gopher-social/internal/mailer"
+	"github.com/ana-tonic/gopher-social/internal/media"
 	"github.com/ana-tonic/gopher-social/internal/ratelimiter"
 	"github.com/ana-tonic/gopher-social/internal/store"
 	"github.com/ana-tonic/gopher-social/internal/store/cache"
@@ -52,7 +53,12 @@ func main() {
 		},
 		env: env.GetString("ENV", ""),
 		mail: mailConfig{
-			exp:       time.Hour * 24 * 3, // 3 days
+			exp:      time.Hour * 24 * 3, // 3 days
+			resetExp: time.Hour,
+			resetLimiter: ratelimiter.Config{
+				RequestsPerTimeFrame: 3,
+				TimeFrame:            time.Hour,
+			},
 			fromEmail: env.GetString("FROM_EMAIL", ""),
 			sendGrid: sendGridConfig{
 				apiKey: env.GetString("SENDGRID_API_KEY", ""),
@@ -67,9 +73,17 @@ func main() {
 				pass: env.GetString("AUTH_BASIC_PASS", "admin"),
 			},
 			token: tokenConfig{
-				secret: env.GetString("AUTH_TOKEN_SECRET", "example"),
-				exp:    time.Hour * 24 * 3, // 3 days
-				iss:    "gophersocial",
+				secret:           env.GetString("AUTH_TOKEN_SECRET", "example"),
+				signingKey:       env.GetString("AUTH_TOKEN_SIGNING_KEY", ""),
+				verificationKeys: env.GetStrings("AUTH_TOKEN_VERIFICATION_KEYS", nil),
+				exp:              time.Minute * 15,
+				refreshExp:       time.Hour * 24 * 30, // 30 days
+				iss:              "gophersocial",
+			},
+			mfa: mfaConfig{
+				issuer:        "GopherSocial",
+				exp:           time.Minute * 5,
+				requiredLevel: env.GetInt("AUTH_MFA_REQUIRED_LEVEL", 0),
 			},
 		},
 		redisCfg: redisConfig{
@@ -83,8 +97,55 @@ func main() {
 			TimeFrame:            time.Second * 5,
 			Enabled:              env.GetBool("RATE_LIMITER_ENABLED", true),
 		},
+		loginGuard: ratelimiter.LoginConfig{
+			BaseDelay:          time.Second,
+			MaxDelay:           time.Second * 30,
+			AccountMaxAttempts: env.GetInt("LOGIN_ACCOUNT_MAX_ATTEMPTS", 5),
+			IPMaxAttempts:      env.GetInt("LOGIN_IP_MAX_ATTEMPTS", 50),
+			LockoutDuration:    time.Minute * 15,
+			Window:             time.Hour,
+		},
+		invitation: invitationConfig{
+			resendLimiter: ratelimiter.Config{
+				RequestsPerTimeFrame: 3,
+				TimeFrame:            time.Hour,
+			},
+			retention:     env.GetDuration("UNACTIVATED_USER_RETENTION", time.Hour*24*7), // 7 days
+			sweepInterval: time.Hour,
+		},
+		media: mediaConfig{
+			backend: env.GetString("MEDIA_BACKEND", "local"),
+			dir:     env.GetString("MEDIA_DIR", "./uploads"),
+			s3: media.S3Config{
+				Endpoint:  env.GetString("S3_ENDPOINT", ""),
+				Region:    env.GetString("S3_REGION", "us-east-1"),
+				Bucket:    env.GetString("S3_BUCKET", ""),
+				AccessKey: env.GetString("S3_ACCESS_KEY", ""),
+				SecretKey: env.GetString("S3_SECRET_KEY", ""),
+			},
+			maxUploadSize: int64(env.GetInt("MEDIA_MAX_UPLOAD_SIZE", 10<<20)), // 10MB
+			userQuota:     int64(env.GetInt("MEDIA_USER_QUOTA", 100<<20)),     // 100MB
+			thumbnailSize: 320,
+		},
+		deletion: deletionConfig{
+			gracePeriod:   env.GetDuration("ACCOUNT_DELETION_GRACE_PERIOD", time.Hour*24*30), // 30 days
+			anonymize:     env.GetBool("ACCOUNT_DELETION_ANONYMIZE", true),
+			sweepInterval: time.Hour,
+		},
+		export: exportConfig{
+			exp:           env.GetDuration("DATA_EXPORT_EXPIRY", time.Hour*24*7), // 7 days
+			sweepInterval: time.Hour,
+		},
+		comments: commentConfig{
+			maxDepth: env.GetInt("COMMENT_MAX_DEPTH", 5),
+		},
+		reactions: reactionConfig{
+			emojis: env.GetStrings("REACTION_EMOJIS", []string{"👍", "❤️", "😂", "😮", "😢", "😡"}),
+		},
 	}
 
+	cfg.auth.oidc = loadOIDCConfig(cfg.apiURL)
+
 	// Logger
 	logger := zap.Must(zap.NewProduction()).Sugar()
 	defer logger.Sync()
@@ -115,6 +176,23 @@ func main() {
 		cfg.rateLimiter.TimeFrame,
 	)
 
+	invitationLimiter := ratelimiter.NewFixedWindowRateLimiter(
+		cfg.invitation.resendLimiter.RequestsPerTimeFrame,
+		cfg.invitation.resendLimiter.TimeFrame,
+	)
+
+	resetLimiter := ratelimiter.NewFixedWindowRateLimiter(
+		cfg.mail.resetLimiter.RequestsPerTimeFrame,
+		cfg.mail.resetLimiter.TimeFrame,
+	)
+
+	// Login brute-force protection
+	var attemptStore ratelimiter.AttemptStore = ratelimiter.NewMemoryAttemptStore()
+	if cfg.redisCfg.enabled {
+		attemptStore = ratelimiter.NewRedisAttemptStore(rdb)
+	}
+	loginGuard := ratelimiter.NewLoginGuard(attemptStore, cfg.loginGuard)
+
 	store := store.NewStorage(db)
 	var cacheStorage cache.Storage
 	if cfg.redisCfg.enabled {
@@ -127,20 +205,57 @@ func main() {
 	// 	logger.Fatal(err)
 	// }
 
-	jwtAuthenticator := auth.NewJWTAuthenticator(
-		cfg.auth.token.secret,
-		cfg.auth.token.iss,
-		cfg.auth.token.iss,
-	)
+	// Authenticator
+	var jwtAuthenticator auth.Authenticator
+	if cfg.auth.token.signingKey != "" {
+		jwtAuthenticator, err = auth.NewKeyPairAuthenticator(
+			cfg.auth.token.signingKey,
+			cfg.auth.token.verificationKeys,
+			cfg.auth.token.iss,
+			cfg.auth.token.iss,
+		)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	} else {
+		jwtAuthenticator = auth.NewJWTAuthenticator(
+			cfg.auth.token.secret,
+			cfg.auth.token.iss,
+			cfg.auth.token.iss,
+		)
+	}
+
+	// Media storage
+	var blobStore media.BlobStore
+	switch cfg.media.backend {
+	case "s3":
+		blobStore = media.NewS3BlobStore(cfg.media.s3, nil)
+	default:
+		blobStore, err = media.NewLocalBlobStore(cfg.media.dir)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	// External identity providers
+	oidcProviders := make(map[string]*auth.OIDCProvider, len(cfg.auth.oidc))
+	for name, providerCfg := range cfg.auth.oidc {
+		oidcProviders[name] = auth.NewOIDCProvider(providerCfg, nil)
+	}
 
 	app := &application{
-		config:        cfg,
-		store:         store,
-		cacheStorage:  cacheStorage,
-		logger:        logger,
-		mailer:        mailer,
-		authenticator: jwtAuthenticator,
-		rateLimiter:   rateLimiter,
+		config:            cfg,
+		store:             store,
+		cacheStorage:      cacheStorage,
+		logger:            logger,
+		mailer:            mailer,
+		authenticator:     jwtAuthenticator,
+		rateLimiter:       rateLimiter,
+		loginGuard:        loginGuard,
+		invitationLimiter: invitationLimiter,
+		resetLimiter:      resetLimiter,
+		oidcProviders:     oidcProviders,
+		blobStore:         blobStore,
 	}
 
 	// Metics collected
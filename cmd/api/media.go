@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/ana-tonic/gopher-social/internal/media"
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// multipartOverhead leaves room for the multipart boundaries and headers on
+// top of the file itself.
+const multipartOverhead = 1 << 16
+
+var (
+	errFileTooLarge = errors.New("file is too large")
+	errUnknownMedia = errors.New("media not found or not owned by you")
+)
+
+// UploadMedia godoc
+//
+//	@Summary		Uploads an image
+//	@Description	Uploads a JPEG, PNG or GIF image in the multipart field "file". The type is detected from the content and a thumbnail is generated
+//	@Tags			media
+//	@Accept			mpfd
+//	@Produce		json
+//	@Param			file	formData	file	true	"Image"
+//	@Success		201		{object}	store.Media
+//	@Failure		400		{object}	error
+//	@Failure		413		{object}	error	"File too large or storage quota exceeded"
+//	@Failure		415		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/media [post]
+func (app *application) uploadMediaHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	ctx := r.Context()
+	cfg := app.config.media
+
+	usage, err := app.store.Media.GetUsage(ctx, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	limit, limitErr := cfg.maxUploadSize, errFileTooLarge
+	if remaining := cfg.userQuota - usage; remaining < limit {
+		limit, limitErr = remaining, store.ErrQuotaExceeded
+	}
+
+	if limit <= 0 {
+		app.payloadTooLargeResponse(w, r, limitErr)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, cfg.maxUploadSize+multipartOverhead)
+
+	data, err := readUpload(r, limit)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			app.payloadTooLargeResponse(w, r, errFileTooLarge)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	if int64(len(data)) > limit {
+		app.payloadTooLargeResponse(w, r, limitErr)
+		return
+	}
+
+	contentType, err := media.Sniff(data)
+	if err != nil {
+		app.unsupportedMediaTypeResponse(w, r, err)
+		return
+	}
+
+	width, height, err := media.Dimensions(data)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	thumbnail, err := media.Thumbnail(data, cfg.thumbnailSize)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	name := fmt.Sprintf("%d/%s", user.ID, uuid.New().String())
+	m := &store.Media{
+		UserID:       user.ID,
+		Key:          name + media.Extension(contentType),
+		ThumbnailKey: name + "_thumb.jpg",
+		ContentType:  contentType,
+		Size:         int64(len(data)),
+		Width:        width,
+		Height:       height,
+	}
+
+	if err := app.blobStore.Put(ctx, m.Key, bytes.NewReader(data), m.Size, m.ContentType); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.blobStore.Put(ctx, m.ThumbnailKey, bytes.NewReader(thumbnail), int64(len(thumbnail)), "image/jpeg"); err != nil {
+		app.deleteBlobs(ctx, m)
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Media.Create(ctx, m, cfg.userQuota); err != nil {
+		app.deleteBlobs(ctx, m)
+		switch err {
+		case store.ErrQuotaExceeded:
+			app.payloadTooLargeResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, m); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetMedia godoc
+//
+//	@Summary		Fetches an image
+//	@Tags			media
+//	@Produce		image/jpeg,image/png,image/gif
+//	@Param			mediaID	path		int	true	"Media ID"
+//	@Success		200		{file}		file
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/media/{mediaID} [get]
+func (app *application) getMediaHandler(w http.ResponseWriter, r *http.Request) {
+	app.serveMedia(w, r, false)
+}
+
+// GetMediaThumbnail godoc
+//
+//	@Summary		Fetches an image thumbnail
+//	@Tags			media
+//	@Produce		image/jpeg
+//	@Param			mediaID	path		int	true	"Media ID"
+//	@Success		200		{file}		file
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/media/{mediaID}/thumbnail [get]
+func (app *application) getMediaThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	app.serveMedia(w, r, true)
+}
+
+// DeleteMedia godoc
+//
+//	@Summary		Deletes an image
+//	@Description	Deletes one of the authenticated user's uploads. It is removed from posts and profiles using it
+//	@Tags			media
+//	@Param			mediaID	path		int		true	"Media ID"
+//	@Success		204		{string}	string	"Media deleted"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/media/{mediaID} [delete]
+func (app *application) deleteMediaHandler(w http.ResponseWriter, r *http.Request) {
+	mediaID, err := strconv.ParseInt(chi.URLParam(r, "mediaID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	m, err := app.store.Media.Delete(ctx, mediaID, user.ID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.deleteBlobs(ctx, m)
+
+	// the avatar reference is cleared by the database
+	if app.config.redisCfg.enabled {
+		app.cacheStorage.Users.Delete(ctx, user.ID)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) serveMedia(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	mediaID, err := strconv.ParseInt(chi.URLParam(r, "mediaID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	m, err := app.store.Media.GetByID(ctx, mediaID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// uploads of hidden or blocking users are reported as not found, as their
+	// posts are
+	visible, err := app.store.Users.CanViewPosts(ctx, m.UserID, getUserFromContext(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if !visible {
+		app.notFoundResponse(w, r, store.ErrNotFound)
+		return
+	}
+
+	key, contentType := m.Key, m.ContentType
+	if thumbnail {
+		key, contentType = m.ThumbnailKey, "image/jpeg"
+	}
+
+	blob, err := app.blobStore.Get(ctx, key)
+	if err != nil {
+		switch err {
+		case media.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// blobs are never overwritten, a new upload gets a new ID
+	w.Header().Set("Cache-Control", "private, max-age=86400, immutable")
+
+	if _, err := io.Copy(w, blob); err != nil {
+		app.logger.Errorw("error streaming media", "media_id", m.ID, "error", err)
+	}
+}
+
+func (app *application) deleteBlobs(ctx context.Context, m *store.Media) {
+	for _, key := range []string{m.Key, m.ThumbnailKey} {
+		if err := app.blobStore.Delete(ctx, key); err != nil {
+			app.logger.Errorw("error deleting blob", "key", key, "error", err)
+		}
+	}
+}
+
+// readUpload returns the contents of the "file" part of a multipart request,
+// reading at most limit+1 bytes so callers can tell the file was too large.
+func readUpload(r *http.Request, limit int64) ([]byte, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, errors.New("missing file")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+		defer part.Close()
+
+		return io.ReadAll(io.LimitReader(part, limit+1))
+	}
+}
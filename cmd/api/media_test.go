@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+)
+
+func TestUploadMedia(t *testing.T) {
+	cfg := config{
+		media: mediaConfig{
+			maxUploadSize: 1 << 20,
+			userQuota:     1 << 20,
+			thumbnailSize: 64,
+		},
+	}
+
+	app := newTestApplication(t, cfg)
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upload := func(t *testing.T, data []byte) int {
+		t.Helper()
+
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, err := mw.CreateFormFile("file", "upload.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := mw.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/media", &body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should accept an image", func(t *testing.T) {
+		var img bytes.Buffer
+		if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 200, 100))); err != nil {
+			t.Fatal(err)
+		}
+
+		checkResponseCode(t, http.StatusCreated, upload(t, img.Bytes()))
+	})
+
+	t.Run("should reject content that is not an image", func(t *testing.T) {
+		checkResponseCode(t, http.StatusUnsupportedMediaType, upload(t, []byte("<html></html>")))
+	})
+
+	t.Run("should reject files over the size limit", func(t *testing.T) {
+		checkResponseCode(t, http.StatusRequestEntityTooLarge, upload(t, make([]byte, 2<<20)))
+	})
+}
+
+func TestGetMedia(t *testing.T) {
+	app := newTestApplication(t, config{})
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := app.blobStore.Put(ctx, "image", bytes.NewReader([]byte("image")), 5, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+
+	app.store.Media = &storedMediaStore{}
+
+	get := func(t *testing.T) int {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, "/v1/media/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, app.mount()).Code
+	}
+
+	t.Run("should serve a visible upload", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, get(t))
+	})
+
+	t.Run("should not serve the upload of a hidden user", func(t *testing.T) {
+		app.store.Users = &hiddenUserStore{}
+
+		checkResponseCode(t, http.StatusNotFound, get(t))
+	})
+}
+
+// storedMediaStore finds every upload, owned by user 2.
+type storedMediaStore struct {
+	store.MockMediaStore
+}
+
+func (m *storedMediaStore) GetByID(ctx context.Context, id int64) (*store.Media, error) {
+	return &store.Media{ID: id, UserID: 2, Key: "image", ContentType: "image/png"}, nil
+}
+
+// hiddenUserStore hides the posts of every user.
+type hiddenUserStore struct {
+	store.MockUserStore
+}
+
+func (m *hiddenUserStore) CanViewPosts(ctx context.Context, authorID, viewerID int64) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ana-tonic/gopher-social/internal/auth"
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mfaTokenType marks the partial token handed out after the password check;
+// it can only be exchanged at /authentication/token/mfa.
+const mfaTokenType = "mfa"
+
+const recoveryCodesCount = 10
+
+var errInvalidMFACode = errors.New("invalid two-factor code")
+
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFACodePayload struct {
+	Code string `json:"code" validate:"required,max=20"`
+}
+
+type VerifyMFAPayload struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required,max=20"`
+}
+
+// VerifyMFAHandler godoc
+//
+//	@Summary		Completes a two-factor login
+//	@Description	Exchanges the mfa_token from /authentication/token and a TOTP or recovery code for a token pair
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		VerifyMFAPayload	true	"MFA token and code"
+//	@Success		201		{object}	TokenPair
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		423		{object}	error	"Account locked"
+//	@Failure		429		{object}	error	"Too many failed attempts, or the client IP is locked"
+//	@Failure		500		{object}	error
+//	@Router			/authentication/token/mfa [post]
+func (app *application) verifyMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var payload VerifyMFAPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	jwtToken, err := app.authenticator.ValidateToken(payload.MFAToken)
+	if err != nil {
+		app.unauthorizedErrorResponse(w, r, err)
+		return
+	}
+
+	claims, _ := jwtToken.Claims.(jwt.MapClaims)
+	if typ, _ := claims["typ"].(string); typ != mfaTokenType {
+		app.unauthorizedErrorResponse(w, r, fmt.Errorf("not an mfa token"))
+		return
+	}
+
+	userID, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 64)
+	if err != nil {
+		app.unauthorizedErrorResponse(w, r, fmt.Errorf("invalid user ID in token claims"))
+		return
+	}
+
+	ctx := r.Context()
+
+	// the account may be deactivated, the login restores it once verified
+	user, err := app.getLoginUser(ctx, userID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	verdict, ok := app.checkLogin(w, r, user.Email)
+	if !ok {
+		return
+	}
+
+	if err := app.verifySecondFactor(ctx, user, payload.Code); err != nil {
+		switch err {
+		case errInvalidMFACode:
+			app.loginFailed(verdict, user.Email, user)
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if !app.reactivateUser(w, r, user) {
+		return
+	}
+
+	app.loginSucceeded(ctx, r, user.Email)
+
+	tokens, err := app.startSession(r, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, tokens); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// EnrollTOTPHandler godoc
+//
+//	@Summary		Starts TOTP enrollment
+//	@Description	Generates a TOTP secret for the user. 2FA is enabled once a code is confirmed
+//	@Tags			authentication
+//	@Produce		json
+//	@Success		201	{object}	TOTPEnrollment
+//	@Failure		400	{object}	error
+//	@Failure		401	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/authentication/mfa/totp [post]
+func (app *application) enrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	if user.TOTPEnabled {
+		app.badRequestResponse(w, r, errors.New("two-factor authentication is already enabled"))
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.MFA.SetTOTPSecret(r.Context(), user.ID, secret); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	enrollment := TOTPEnrollment{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(app.config.auth.mfa.issuer, user.Email, secret),
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, enrollment); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ConfirmTOTPHandler godoc
+//
+//	@Summary		Confirms TOTP enrollment
+//	@Description	Enables 2FA after checking a code from the authenticator app and returns one-time recovery codes
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		MFACodePayload	true	"TOTP code"
+//	@Success		200		{object}	RecoveryCodes
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/authentication/mfa/totp/confirm [post]
+func (app *application) confirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	var payload MFACodePayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	// the cached user does not carry the secret
+	user, err := app.store.Users.GetByID(ctx, getUserFromContext(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if user.TOTPSecret == "" || user.TOTPEnabled {
+		app.badRequestResponse(w, r, errors.New("no pending two-factor enrollment"))
+		return
+	}
+
+	if err := app.verifyTOTP(ctx, user, payload.Code); err != nil {
+		switch err {
+		case errInvalidMFACode:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.MFA.EnableTOTP(ctx, user.ID, codes); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if app.config.redisCfg.enabled {
+		app.cacheStorage.Users.Delete(ctx, user.ID)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, RecoveryCodes{RecoveryCodes: codes}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DisableTOTPHandler godoc
+//
+//	@Summary		Disables TOTP
+//	@Description	Turns off 2FA after checking a TOTP or recovery code. Not allowed for roles where 2FA is mandatory
+//	@Tags			authentication
+//	@Accept			json
+//	@Param			payload	body		MFACodePayload	true	"TOTP or recovery code"
+//	@Success		204		{string}	string			"2FA disabled"
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/authentication/mfa/totp [delete]
+func (app *application) disableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	var payload MFACodePayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	user, err := app.store.Users.GetByID(ctx, getUserFromContext(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if app.mfaRequired(user) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	if !user.TOTPEnabled {
+		app.badRequestResponse(w, r, errors.New("two-factor authentication is not enabled"))
+		return
+	}
+
+	if err := app.verifySecondFactor(ctx, user, payload.Code); err != nil {
+		switch err {
+		case errInvalidMFACode:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.store.MFA.DisableTOTP(ctx, user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if app.config.redisCfg.enabled {
+		app.cacheStorage.Users.Delete(ctx, user.ID)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) newMFAChallenge(user *store.User) (*MFAChallenge, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub": user.ID,
+		"typ": mfaTokenType,
+		"exp": now.Add(app.config.auth.mfa.exp).Unix(),
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"iss": app.config.auth.token.iss,
+		"aud": app.config.auth.token.iss,
+	}
+
+	token, err := app.authenticator.GenerateToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAChallenge{MFARequired: true, MFAToken: token}, nil
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused
+// recovery code.
+func (app *application) verifySecondFactor(ctx context.Context, user *store.User, code string) error {
+	if len(code) == 6 {
+		return app.verifyTOTP(ctx, user, code)
+	}
+
+	if err := app.store.MFA.UseRecoveryCode(ctx, user.ID, code); err != nil {
+		if err == store.ErrNotFound {
+			return errInvalidMFACode
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (app *application) verifyTOTP(ctx context.Context, user *store.User, code string) error {
+	step, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return errInvalidMFACode
+	}
+
+	if err := app.store.MFA.UseTOTPStep(ctx, user.ID, step); err != nil {
+		if err == store.ErrConflict {
+			return errInvalidMFACode
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (app *application) mfaRequired(user *store.User) bool {
+	level := app.config.auth.mfa.requiredLevel
+	return level > 0 && user.Role.Level >= level
+}
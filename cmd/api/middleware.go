@@ -13,6 +13,17 @@ import (
 )
 
 func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
+	return app.authTokenMiddleware(next, true)
+}
+
+// MFAEnrollmentMiddleware authenticates like AuthTokenMiddleware but lets
+// through users that still have to enroll in mandatory two-factor
+// authentication.
+func (app *application) MFAEnrollmentMiddleware(next http.Handler) http.Handler {
+	return app.authTokenMiddleware(next, false)
+}
+
+func (app *application) authTokenMiddleware(next http.Handler, enforceMFA bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
@@ -21,11 +32,23 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 		}
 
 		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
+		if len(parts) != 2 || (parts[0] != "Bearer" && parts[0] != "ApiKey") {
 			app.unauthorizedErrorResponse(w, r, fmt.Errorf("authorization header is malformed"))
 			return
 		}
 
+		// API keys are created from a fully authenticated session, so they are
+		// never accepted while 2FA enrollment is still pending
+		if parts[0] == "ApiKey" {
+			if !enforceMFA {
+				app.unauthorizedErrorResponse(w, r, fmt.Errorf("api keys are not accepted here"))
+				return
+			}
+
+			app.apiKeyAuth(w, r, next, parts[1])
+			return
+		}
+
 		token := parts[1]
 		jwtToken, err := app.authenticator.ValidateToken(token)
 		if err != nil {
@@ -35,25 +58,118 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 
 		claims, _ := jwtToken.Claims.(jwt.MapClaims)
 
+		if typ, _ := claims["typ"].(string); typ == mfaTokenType {
+			app.unauthorizedErrorResponse(w, r, fmt.Errorf("mfa token used as access token"))
+			return
+		}
+
 		userID, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 64)
 		if err != nil {
 			app.unauthorizedErrorResponse(w, r, fmt.Errorf("invalid user ID in token claims"))
 			return
 		}
 
+		sessionID, _ := claims["sid"].(string)
+		if sessionID == "" {
+			app.unauthorizedErrorResponse(w, r, fmt.Errorf("missing session in token claims"))
+			return
+		}
+
 		ctx := r.Context()
 
+		session, err := app.getSession(ctx, sessionID)
+		if err != nil {
+			app.unauthorizedErrorResponse(w, r, err)
+			return
+		}
+
+		if session.RevokedAt != nil || session.UserID != userID {
+			app.unauthorizedErrorResponse(w, r, fmt.Errorf("session %s is no longer valid", sessionID))
+			return
+		}
+
 		user, err := app.getUser(ctx, userID)
 		if err != nil {
 			app.unauthorizedErrorResponse(w, r, err)
 			return
 		}
 
+		if enforceMFA && app.mfaRequired(user) && !user.TOTPEnabled {
+			app.forbiddenResponse(w, r)
+			return
+		}
+
+		app.touchSession(ctx, session, r)
+
 		ctx = context.WithValue(ctx, userCtx, user)
+		ctx = context.WithValue(ctx, sessionCtx, session.ID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+func (app *application) apiKeyAuth(w http.ResponseWriter, r *http.Request, next http.Handler, token string) {
+	ctx := r.Context()
+
+	key, err := app.store.APIKeys.GetByToken(ctx, token)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.getUser(ctx, key.UserID)
+	if err != nil {
+		app.unauthorizedErrorResponse(w, r, err)
+		return
+	}
+
+	// a key minted before 2FA became mandatory for the user's role must not
+	// get around enrolling
+	if app.mfaRequired(user) && !user.TOTPEnabled {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	app.touchAPIKey(ctx, key)
+
+	ctx = context.WithValue(ctx, userCtx, user)
+	ctx = context.WithValue(ctx, apiKeyCtx, key)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// requireScope rejects requests authenticated with an API key that was not
+// granted the scope. Every route behind AuthTokenMiddleware must declare its
+// scope or use requireUserToken.
+func (app *application) requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key := getAPIKeyFromContext(r); key != nil && !key.HasScope(scope) {
+				app.forbiddenResponse(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireUserToken rejects requests authenticated with an API key, for routes
+// that manage the account itself.
+func (app *application) requireUserToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if getAPIKeyFromContext(r) != nil {
+			app.forbiddenResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) BasicAuthMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -95,27 +211,57 @@ func (app *application) BasicAuthMiddleware() func(http.Handler) http.Handler {
 
 func (app *application) checkPostOwnership(requiredRole string, next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		user := getUserFromContext(r)
-		post := getPostFromContext(r)
-
-		if post.UserID == user.ID {
-			next.ServeHTTP(w, r)
-			return
-		}
+		app.checkOwnership(w, r, getPostFromContext(r).UserID, requiredRole, next)
+	})
+}
 
-		allowed, err := app.checkRolePrecedence(r.Context(), user, requiredRole)
-		if err != nil {
-			app.internalServerError(w, r, err)
-			return
-		}
+func (app *application) checkCommentOwnership(requiredRole string, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.checkOwnership(w, r, getCommentFromContext(r).UserID, requiredRole, next)
+	})
+}
 
-		if !allowed {
-			app.forbiddenResponse(w, r)
-			return
-		}
+// checkOwnership lets the owner, or users with at least requiredRole, through
+// to next.
+func (app *application) checkOwnership(w http.ResponseWriter, r *http.Request, ownerID int64, requiredRole string, next http.HandlerFunc) {
+	user := getUserFromContext(r)
 
+	if ownerID == user.ID {
 		next.ServeHTTP(w, r)
-	})
+		return
+	}
+
+	allowed, err := app.checkRolePrecedence(r.Context(), user, requiredRole)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if !allowed {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	next.ServeHTTP(w, r)
+}
+
+func (app *application) requireRole(roleName string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, err := app.checkRolePrecedence(r.Context(), getUserFromContext(r), roleName)
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+
+			if !allowed {
+				app.forbiddenResponse(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
 func (app *application) checkRolePrecedence(ctx context.Context, user *store.User, roleName string) (bool, error) {
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ana-tonic/gopher-social/internal/auth"
+	"github.com/ana-tonic/gopher-social/internal/env"
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	oidcCookieName = "oidc_flow"
+	oidcFlowExp    = time.Minute * 10
+	// how many random suffixes to try when the derived username is taken
+	oidcUsernameAttempts = 3
+)
+
+var (
+	errUnknownOIDCProvider = errors.New("unknown identity provider")
+	errInvalidOIDCState    = errors.New("invalid or expired login state")
+	errUnverifiedEmail     = errors.New("identity provider did not verify the email address")
+
+	usernameDisallowed = regexp.MustCompile(`[^a-z0-9_.-]+`)
+)
+
+// oidcFlow is kept in a short-lived cookie between the start and callback
+// requests so the callback can be tied back to the browser that started it.
+type oidcFlow struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// loadOIDCConfig reads the providers listed in OIDC_PROVIDERS, each configured
+// through OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID and OIDC_<NAME>_CLIENT_SECRET.
+func loadOIDCConfig(apiURL string) map[string]auth.OIDCConfig {
+	providers := make(map[string]auth.OIDCConfig)
+
+	for _, name := range env.GetStrings("OIDC_PROVIDERS", nil) {
+		name = strings.ToLower(name)
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		providers[name] = auth.OIDCConfig{
+			Issuer:       env.GetString(prefix+"ISSUER", ""),
+			ClientID:     env.GetString(prefix+"CLIENT_ID", ""),
+			ClientSecret: env.GetString(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  fmt.Sprintf("%s/v1/authentication/oidc/%s/callback", apiURL, name),
+		}
+	}
+
+	return providers
+}
+
+// StartOIDCLogin godoc
+//
+//	@Summary		Starts an OpenID Connect login
+//	@Description	Redirects to the identity provider using the authorization code flow with PKCE
+//	@Tags			authentication
+//	@Param			provider	path		string	true	"Provider name"
+//	@Success		302			{string}	string	"Redirect to the provider"
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Router			/authentication/oidc/{provider}/start [get]
+func (app *application) startOIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+
+	provider, ok := app.oidcProviders[name]
+	if !ok {
+		app.notFoundResponse(w, r, errUnknownOIDCProvider)
+		return
+	}
+
+	state, nonce, err := auth.GenerateOIDCState()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	verifier, challenge, err := auth.GeneratePKCE()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), state, nonce, challenge)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	flow, err := json.Marshal(oidcFlow{State: state, Nonce: nonce, Verifier: verifier})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(flow),
+		Path:     oidcCookiePath(name),
+		MaxAge:   int(oidcFlowExp.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback godoc
+//
+//	@Summary		Completes an OpenID Connect login
+//	@Description	Exchanges the authorization code, then signs in the linked user. New identities are linked to the user with the same verified email or get a new account. Signing in restores a deactivated account
+//	@Tags			authentication
+//	@Produce		json
+//	@Param			provider	path		string	true	"Provider name"
+//	@Param			code		query		string	true	"Authorization code"
+//	@Param			state		query		string	true	"State from the start request"
+//	@Success		201			{object}	TokenPair
+//	@Success		202			{object}	MFAChallenge	"Second factor required"
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Router			/authentication/oidc/{provider}/callback [get]
+func (app *application) oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+
+	provider, ok := app.oidcProviders[name]
+	if !ok {
+		app.notFoundResponse(w, r, errUnknownOIDCProvider)
+		return
+	}
+
+	flow, err := readOIDCFlow(r)
+	// the state is single use
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookieName,
+		Path:     oidcCookiePath(name),
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+	if err != nil {
+		app.unauthorizedErrorResponse(w, r, err)
+		return
+	}
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		app.unauthorizedErrorResponse(w, r, fmt.Errorf("identity provider returned %s", e))
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(flow.State)) != 1 {
+		app.unauthorizedErrorResponse(w, r, errInvalidOIDCState)
+		return
+	}
+
+	if q.Get("code") == "" {
+		app.badRequestResponse(w, r, errors.New("missing authorization code"))
+		return
+	}
+
+	ctx := r.Context()
+
+	claims, err := provider.Exchange(ctx, q.Get("code"), flow.Verifier)
+	if err != nil {
+		app.unauthorizedErrorResponse(w, r, err)
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(flow.Nonce)) != 1 {
+		app.unauthorizedErrorResponse(w, r, errInvalidOIDCState)
+		return
+	}
+
+	user, err := app.userForIdentity(ctx, name, claims)
+	if err != nil {
+		switch err {
+		case errUnverifiedEmail, store.ErrNotFound:
+			app.unauthorizedErrorResponse(w, r, err)
+		case store.ErrDuplicateEmail, store.ErrDuplicateUsername, store.ErrConflict:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// the provider stands in for the password, not for the second factor
+	if user.TOTPEnabled {
+		challenge, err := app.newMFAChallenge(user)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if err := app.jsonResponse(w, http.StatusAccepted, challenge); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if !app.reactivateUser(w, r, user) {
+		return
+	}
+
+	tokens, err := app.startSession(r, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, tokens); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// userForIdentity returns the user linked to the provider subject. Unknown
+// subjects are linked to the user with the same verified email, or to a newly
+// created user. The user may be deactivated, in which case logging in
+// restores the account.
+func (app *application) userForIdentity(ctx context.Context, provider string, claims *auth.OIDCClaims) (*store.User, error) {
+	userID, err := app.store.Identities.GetUserID(ctx, provider, claims.Subject)
+	switch err {
+	case nil:
+		return app.getLoginUser(ctx, userID)
+	case store.ErrNotFound:
+	default:
+		return nil, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errUnverifiedEmail
+	}
+
+	identity := &store.Identity{
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+
+	user, err := app.store.Users.GetByEmail(ctx, claims.Email)
+	if err == store.ErrNotFound {
+		user, err = app.store.Users.GetDeactivatedByEmail(ctx, claims.Email)
+	}
+	switch err {
+	case nil:
+		identity.UserID = user.ID
+		if err := app.store.Identities.Create(ctx, identity); err != nil {
+			return nil, err
+		}
+
+		app.logger.Infow("linked identity to existing user", "user_id", user.ID, "provider", provider)
+
+		return app.getLoginUser(ctx, user.ID)
+	case store.ErrNotFound:
+	default:
+		return nil, err
+	}
+
+	return app.createUserForIdentity(ctx, identity, claims)
+}
+
+func (app *application) createUserForIdentity(ctx context.Context, identity *store.Identity, claims *auth.OIDCClaims) (*store.User, error) {
+	// the account can only be used through the provider until a password is
+	// set with the reset flow
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return nil, err
+	}
+
+	base := oidcUsername(claims)
+
+	for attempt := 0; ; attempt++ {
+		username := base
+		if attempt > 0 {
+			suffix := make([]byte, 3)
+			if _, err := rand.Read(suffix); err != nil {
+				return nil, err
+			}
+			username = base + "_" + hex.EncodeToString(suffix)
+		}
+
+		user := &store.User{
+			Username: username,
+			Email:    claims.Email,
+			Role: store.Role{
+				Name: "user",
+			},
+		}
+
+		if err := user.Password.Set(hex.EncodeToString(password)); err != nil {
+			return nil, err
+		}
+
+		err := app.store.Users.CreateWithIdentity(ctx, user, identity)
+		if err == store.ErrDuplicateUsername && attempt < oidcUsernameAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		app.logger.Infow("created user from identity", "user_id", user.ID, "provider", identity.Provider)
+
+		return user, nil
+	}
+}
+
+// oidcUsername derives a username from the preferred username or the local
+// part of the email.
+func oidcUsername(claims *auth.OIDCClaims) string {
+	name := claims.PreferredUsername
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	name = usernameDisallowed.ReplaceAllString(strings.ToLower(name), "")
+	if len(name) > 50 {
+		name = name[:50]
+	}
+	if name == "" || isReservedUsername(name) {
+		name = "gopher"
+	}
+
+	return name
+}
+
+func readOIDCFlow(r *http.Request) (*oidcFlow, error) {
+	cookie, err := r.Cookie(oidcCookieName)
+	if err != nil {
+		return nil, errInvalidOIDCState
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return nil, errInvalidOIDCState
+	}
+
+	var flow oidcFlow
+	if err := json.Unmarshal(data, &flow); err != nil || flow.State == "" {
+		return nil, errInvalidOIDCState
+	}
+
+	return &flow, nil
+}
+
+func oidcCookiePath(provider string) string {
+	return "/v1/authentication/oidc/" + provider
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ana-tonic/gopher-social/internal/auth"
+	"github.com/ana-tonic/gopher-social/internal/store"
+)
+
+func TestOIDCLogin(t *testing.T) {
+	idp := auth.NewTestOIDCServer()
+	defer idp.Close()
+
+	app := newTestApplication(t, config{})
+	srv := httptest.NewServer(app.mount())
+	defer srv.Close()
+
+	app.oidcProviders = map[string]*auth.OIDCProvider{
+		"test": auth.NewOIDCProvider(auth.OIDCConfig{
+			Issuer:       idp.URL,
+			ClientID:     idp.ClientID,
+			ClientSecret: idp.ClientSecret,
+			RedirectURL:  srv.URL + "/v1/authentication/oidc/test/callback",
+		}, nil),
+	}
+
+	newClient := func(t *testing.T) *http.Client {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &http.Client{Jar: jar}
+	}
+
+	t.Run("should sign in through the provider", func(t *testing.T) {
+		resp, err := newClient(t).Get(srv.URL + "/v1/authentication/oidc/test/start")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		checkResponseCode(t, http.StatusCreated, resp.StatusCode)
+
+		var body struct {
+			Data TokenPair `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if body.Data.Token == "" || body.Data.RefreshToken == "" {
+			t.Errorf("expected a token pair, got %+v", body.Data)
+		}
+	})
+
+	t.Run("should reject a callback without the state cookie", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/v1/authentication/oidc/test/callback?code=abc&state=xyz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		checkResponseCode(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("should reject an unverified email", func(t *testing.T) {
+		idp.SetClaims(map[string]any{"email_verified": false})
+		defer idp.SetClaims(map[string]any{"email_verified": true})
+
+		resp, err := newClient(t).Get(srv.URL + "/v1/authentication/oidc/test/start")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		checkResponseCode(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("should report a conflict when no free username is found", func(t *testing.T) {
+		users := app.store.Users
+		app.store.Users = &takenUsernameStore{}
+		defer func() { app.store.Users = users }()
+
+		resp, err := newClient(t).Get(srv.URL + "/v1/authentication/oidc/test/start")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		checkResponseCode(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	loginDeactivated := func(t *testing.T, deactivated *deactivatedUserStore, linked bool) int {
+		t.Helper()
+
+		users, identities := app.store.Users, app.store.Identities
+		defer func() { app.store.Users, app.store.Identities = users, identities }()
+
+		app.store.Users = deactivated
+		if linked {
+			app.store.Identities = &linkedIdentityStore{}
+		}
+
+		resp, err := newClient(t).Get(srv.URL + "/v1/authentication/oidc/test/start")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	t.Run("should restore a deactivated account linked to the identity", func(t *testing.T) {
+		deactivated := &deactivatedUserStore{}
+		checkResponseCode(t, http.StatusCreated, loginDeactivated(t, deactivated, true))
+
+		if !deactivated.reactivated {
+			t.Error("expected the account to be restored")
+		}
+	})
+
+	t.Run("should link and restore a deactivated account with the same email", func(t *testing.T) {
+		deactivated := &deactivatedUserStore{}
+		checkResponseCode(t, http.StatusCreated, loginDeactivated(t, deactivated, false))
+
+		if !deactivated.reactivated {
+			t.Error("expected the account to be restored")
+		}
+	})
+
+	t.Run("should not restore an account with 2FA before the second factor", func(t *testing.T) {
+		deactivated := &deactivatedUserStore{totpEnabled: true}
+		checkResponseCode(t, http.StatusAccepted, loginDeactivated(t, deactivated, true))
+
+		if deactivated.reactivated {
+			t.Error("expected the account to stay deactivated")
+		}
+	})
+
+	t.Run("should return 404 for an unknown provider", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/authentication/oidc/nope/start", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, app.mount())
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+// takenUsernameStore rejects every username of new accounts.
+type takenUsernameStore struct {
+	store.MockUserStore
+}
+
+func (m *takenUsernameStore) CreateWithIdentity(ctx context.Context, user *store.User, identity *store.Identity) error {
+	return store.ErrDuplicateUsername
+}
+
+// linkedIdentityStore links every identity to user 1.
+type linkedIdentityStore struct {
+	store.MockIdentityStore
+}
+
+func (m *linkedIdentityStore) GetUserID(ctx context.Context, provider, subject string) (int64, error) {
+	return 1, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ana-tonic/gopher-social/internal/mailer"
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/google/uuid"
+)
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// ForgotPasswordHandler godoc
+//
+//	@Summary		Requests a password reset
+//	@Description	Emails a single-use password reset link. The response is the same whether or not the email is registered, and when too many links were requested for it
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		ForgotPasswordPayload	true	"Account email"
+//	@Success		202		{string}	string					"Reset requested"
+//	@Failure		400		{object}	error
+//	@Router			/authentication/password/forgot [post]
+func (app *application) forgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ForgotPasswordPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// the lookup, token and email all happen in the background so neither the
+	// status nor the response time tells whether the email is registered. Over
+	// the limit nothing is sent, with the same response.
+	if allow, _ := app.resetLimiter.Allow(strings.ToLower(payload.Email)); allow {
+		go app.sendPasswordReset(payload.Email)
+	}
+
+	msg := "if the email is registered, a password reset link has been sent"
+	if err := app.jsonResponse(w, http.StatusAccepted, msg); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) sendPasswordReset(email string) {
+	ctx := context.Background()
+
+	user, err := app.store.Users.GetByEmail(ctx, email)
+	if err != nil {
+		if err != store.ErrNotFound {
+			app.logger.Errorw("error fetching user for password reset", "error", err)
+		}
+		return
+	}
+
+	plainToken := uuid.New().String()
+
+	if err := app.store.Users.CreatePasswordReset(ctx, user.ID, plainToken, app.config.mail.resetExp); err != nil {
+		app.logger.Errorw("error creating password reset", "error", err)
+		return
+	}
+
+	isProdEnv := app.config.env == "production"
+	vars := struct {
+		Username  string
+		ResetURL  string
+		ExpiresIn string
+	}{
+		Username:  user.Username,
+		ResetURL:  fmt.Sprintf("%s/reset-password/%s", app.config.frontendURL, plainToken),
+		ExpiresIn: app.config.mail.resetExp.String(),
+	}
+
+	status, err := app.mailer.Send(mailer.PasswordResetTemplate, user.Username, user.Email, vars, !isProdEnv)
+	if err != nil {
+		app.logger.Errorw("error sending password reset email", "error", err)
+		return
+	}
+
+	app.logger.Infow("Email sent with status code", "status", status)
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token" validate:"required,max=255"`
+	Password string `json:"password" validate:"required,min=3,max=72"`
+}
+
+// ResetPasswordHandler godoc
+//
+//	@Summary		Resets a password
+//	@Description	Sets a new password using a reset token and signs the user out of every session
+//	@Tags			authentication
+//	@Accept			json
+//	@Param			payload	body		ResetPasswordPayload	true	"Reset token and new password"
+//	@Success		204		{string}	string					"Password reset"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/authentication/password/reset [post]
+func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ResetPasswordPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	userID, err := app.store.Users.ResetPassword(r.Context(), payload.Token, payload.Password)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.forgetUserSessions(r.Context(), userID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
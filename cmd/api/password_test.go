@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ana-tonic/gopher-social/internal/ratelimiter"
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/ana-tonic/gopher-social/internal/store/cache"
+)
+
+func TestForgotPassword(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	t.Run("should not reveal whether the email is registered", func(t *testing.T) {
+		body := bytes.NewBufferString(`{"email":"nobody@example.com"}`)
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/password/forgot", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusAccepted, rr.Code)
+	})
+
+	t.Run("should stop sending links to an email over the limit", func(t *testing.T) {
+		app := newTestApplication(t, config{
+			mail: mailConfig{resetLimiter: ratelimiter.Config{RequestsPerTimeFrame: 1, TimeFrame: time.Hour}},
+		})
+		users := &lookupUserStore{lookups: make(chan string, 2)}
+		app.store.Users = users
+		mux := app.mount()
+
+		for _, email := range []string{"gopher@example.com", "Gopher@example.com"} {
+			body := bytes.NewBufferString(`{"email":"` + email + `"}`)
+			req, err := http.NewRequest(http.MethodPost, "/v1/authentication/password/forgot", body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := executeRequest(req, mux)
+			checkResponseCode(t, http.StatusAccepted, rr.Code)
+		}
+
+		select {
+		case <-users.lookups:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the first reset")
+		}
+
+		select {
+		case email := <-users.lookups:
+			t.Errorf("expected no reset over the limit, got one for %s", email)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("should reject an invalid email", func(t *testing.T) {
+		body := bytes.NewBufferString(`{"email":"not-an-email"}`)
+		req, err := http.NewRequest(http.MethodPost, "/v1/authentication/password/forgot", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	app := newTestApplication(t, config{redisCfg: redisConfig{enabled: true}})
+	mux := app.mount()
+
+	mockSessionCache := app.cacheStorage.Sessions.(*cache.MockSessionStore)
+	mockSessionCache.On("DeleteForUser", int64(1)).Return(nil)
+
+	body := bytes.NewBufferString(`{"token":"reset-token","password":"new-password"}`)
+	req, err := http.NewRequest(http.MethodPost, "/v1/authentication/password/reset", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := executeRequest(req, mux)
+	checkResponseCode(t, http.StatusNoContent, rr.Code)
+
+	// the sessions revoked with the reset must stop working right away
+	mockSessionCache.AssertCalled(t, "DeleteForUser", int64(1))
+}
+
+// lookupUserStore reports every email looked up to send a password reset.
+type lookupUserStore struct {
+	store.MockUserStore
+	lookups chan string
+}
+
+func (m *lookupUserStore) GetByEmail(ctx context.Context, email string) (*store.User, error) {
+	m.lookups <- email
+	return nil, store.ErrNotFound
+}
@@ -14,13 +14,16 @@ type postKey string
 const postCtx postKey = "post"
 
 type CreatePostPayload struct {
-	Title   string   `json:"title" validate:"required,max=100"`
-	Content string   `json:"content" validate:"required,max=1000"`
-	Tags    []string `json:"tags"`
+	Title    string   `json:"title" validate:"required,max=100"`
+	Content  string   `json:"content" validate:"required,max=1000"`
+	Tags     []string `json:"tags"`
+	MediaIDs []int64  `json:"media_ids" validate:"max=4,unique,dive,min=1"`
+	// QuoteOfID makes the post a quote of another post
+	QuoteOfID *int64 `json:"quote_of_id" validate:"omitempty,min=1"`
 }
 
 // @Summary		Creates a post
-// @Description	Creates a post
+// @Description	Creates a post, which quotes another post when quote_of_id is set
 // @Tags			posts
 // @Accept			json
 // @Produce		json
@@ -28,6 +31,7 @@ type CreatePostPayload struct {
 // @Success		201		{object}	store.Post
 // @Failure		400		{object}	error
 // @Failure		401		{object}	error
+// @Failure		403		{object}	error	"Blocked by or blocking the author of the quoted post"
 // @Failure		500		{object}	error
 // @Security		ApiKeyAuth
 // @Router			/posts [post]
@@ -47,16 +51,31 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 	user := getUserFromContext(r)
 
 	post := &store.Post{
-		Title:   payload.Title,
-		Content: payload.Content,
-		Tags:    payload.Tags,
-		UserID:  user.ID,
+		Title:     payload.Title,
+		Content:   payload.Content,
+		Tags:      payload.Tags,
+		UserID:    user.ID,
+		Media:     make([]store.Media, len(payload.MediaIDs)),
+		QuoteOfID: payload.QuoteOfID,
+	}
+
+	for i, id := range payload.MediaIDs {
+		post.Media[i].ID = id
 	}
 
 	ctx := r.Context()
 
 	if err := app.store.Posts.Create(ctx, post); err != nil {
-		app.internalServerError(w, r, err)
+		switch err {
+		case store.ErrNotFound:
+			app.badRequestResponse(w, r, errUnknownMedia)
+		case store.ErrQuoteNotFound, store.ErrPrivatePost:
+			app.badRequestResponse(w, r, err)
+		case store.ErrBlocked:
+			app.forbiddenResponse(w, r)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
@@ -80,14 +99,45 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 // @Router			/posts/{postID} [get]
 func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromContext(r)
+	viewer := getUserFromContext(r)
+	ctx := r.Context()
+
+	if !app.checkPostVisibility(w, r, post) {
+		return
+	}
+
+	if post.Original != nil && !post.Original.Unavailable {
+		visible, err := app.store.Users.CanViewPosts(ctx, post.Original.UserID, viewer.ID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !visible {
+			post.Original = &store.OriginalPost{Unavailable: true}
+		}
+	}
 
-	comments, err := app.store.Comments.GetByPostID(r.Context(), post.ID)
+	// only the first page is embedded, the rest is fetched from the comments
+	// endpoint with NextCommentsCursor
+	comments, next, err := app.store.Comments.GetByPostID(ctx, post.ID, viewer.ID, store.CursorQuery{Limit: 20})
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
 	post.Comments = comments
+	post.NextCommentsCursor = next
+
+	emoji, err := app.store.Reactions.Get(ctx, store.PostReaction, post.ID, viewer.ID)
+	switch err {
+	case nil:
+		post.MyReaction = &emoji
+	case store.ErrNotFound:
+	default:
+		app.internalServerError(w, r, err)
+		return
+	}
 
 	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
@@ -144,11 +194,17 @@ type UpdatePostPayload struct {
 // @Success		200		{object}	store.Post
 // @Failure		400		{object}	nil
 // @Failure		404		{object}	nil
+// @Failure		409		{object}	nil	"The post was edited in the meantime"
 // @Failure		500		{object}	nil
 // @Router			/posts/{postID} [patch]
 func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromContext(r)
 
+	if post.RepostOfID != nil {
+		app.badRequestResponse(w, r, errEditRepost)
+		return
+	}
+
 	var payload UpdatePostPayload
 
 	if err := readJSON(w, r, &payload); err != nil {
@@ -170,7 +226,12 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	if err := app.store.Posts.Update(r.Context(), post); err != nil {
-		app.internalServerError(w, r, err)
+		switch err {
+		case store.ErrNotFound:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
@@ -207,6 +268,23 @@ func (app *application) postsContextMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// checkPostVisibility responds with not found, without revealing that the
+// post exists, when the authenticated user is not allowed to see it.
+func (app *application) checkPostVisibility(w http.ResponseWriter, r *http.Request, post *store.Post) bool {
+	visible, err := app.store.Users.CanViewPosts(r.Context(), post.UserID, getUserFromContext(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return false
+	}
+
+	if !visible {
+		app.notFoundResponse(w, r, store.ErrNotFound)
+		return false
+	}
+
+	return true
+}
+
 // getPostFromContext godoc
 func getPostFromContext(r *http.Request) *store.Post {
 	return r.Context().Value(postCtx).(*store.Post)
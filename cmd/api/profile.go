@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+)
+
+type UpdateProfilePayload struct {
+	DisplayName        *string `json:"display_name" validate:"omitempty,max=50,notreserved"`
+	Bio                *string `json:"bio" validate:"omitempty,max=160"`
+	Website            *string `json:"website" validate:"omitempty,max=255,http_url|len=0"`
+	Location           *string `json:"location" validate:"omitempty,max=100"`
+	Birthday           *string `json:"birthday" validate:"omitempty,datetime=2006-01-02|len=0"`
+	BirthdayVisibility *string `json:"birthday_visibility" validate:"omitempty,oneof=public private"`
+	// AvatarID is the ID of an uploaded image, 0 removes the avatar
+	AvatarID  *int64 `json:"avatar_id" validate:"omitempty,min=0"`
+	IsPrivate *bool  `json:"is_private"`
+}
+
+// SelfUser is the authenticated user as they see themselves, with the
+// account settings hidden from others.
+type SelfUser struct {
+	*store.User
+	TOTPEnabled bool `json:"totp_enabled"`
+}
+
+func newSelfUser(user *store.User) SelfUser {
+	return SelfUser{User: user, TOTPEnabled: user.TOTPEnabled}
+}
+
+// GetCurrentUser godoc
+//
+//	@Summary		Fetches the authenticated user
+//	@Description	Fetches the authenticated user's profile along with their account settings
+//	@Tags			users
+//	@Produce		json
+//	@Success		200	{object}	SelfUser
+//	@Failure		401	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me [get]
+func (app *application) getCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, newSelfUser(getUserFromContext(r))); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UpdateProfile godoc
+//
+//	@Summary		Updates the user profile
+//	@Description	Updates the authenticated user's profile. Omitted fields are left unchanged and empty strings clear a field
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		UpdateProfilePayload	true	"Profile fields"
+//	@Success		200		{object}	SelfUser
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me [patch]
+func (app *application) updateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateProfilePayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	// the user in the context may come from the cache
+	user, err := app.store.Users.GetByID(ctx, getUserFromContext(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if payload.DisplayName != nil {
+		user.DisplayName = *payload.DisplayName
+	}
+
+	if payload.Bio != nil {
+		user.Bio = *payload.Bio
+	}
+
+	if payload.Website != nil {
+		user.Website = *payload.Website
+	}
+
+	if payload.Location != nil {
+		user.Location = *payload.Location
+	}
+
+	if payload.Birthday != nil {
+		if *payload.Birthday == "" {
+			user.Birthday = nil
+		} else {
+			birthday, _ := time.Parse(time.DateOnly, *payload.Birthday)
+			if birthday.After(time.Now()) {
+				app.badRequestResponse(w, r, errors.New("birthday cannot be in the future"))
+				return
+			}
+			user.Birthday = payload.Birthday
+		}
+	}
+
+	if payload.BirthdayVisibility != nil {
+		user.BirthdayVisibility = *payload.BirthdayVisibility
+	}
+
+	if payload.AvatarID != nil {
+		if *payload.AvatarID == 0 {
+			user.AvatarID = nil
+		} else {
+			avatar, err := app.store.Media.GetByID(ctx, *payload.AvatarID)
+			switch {
+			case err == store.ErrNotFound || (err == nil && avatar.UserID != user.ID):
+				app.badRequestResponse(w, r, errUnknownMedia)
+				return
+			case err != nil:
+				app.internalServerError(w, r, err)
+				return
+			}
+			user.AvatarID = &avatar.ID
+		}
+	}
+
+	if payload.IsPrivate != nil {
+		user.IsPrivate = *payload.IsPrivate
+	}
+
+	if err := app.store.Users.UpdateProfile(ctx, user); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if app.config.redisCfg.enabled {
+		app.cacheStorage.Users.Delete(ctx, user.ID)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, newSelfUser(user)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// profileFor returns the user as the viewer is allowed to see it, hiding a
+// private birthday from everyone but the user.
+func profileFor(user, viewer *store.User) *store.User {
+	if user.Birthday == nil || user.BirthdayVisibility == store.BirthdayPublic {
+		return user
+	}
+
+	if viewer != nil && viewer.ID == user.ID {
+		return user
+	}
+
+	visible := *user
+	visible.Birthday = nil
+
+	return &visible
+}
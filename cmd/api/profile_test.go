@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ana-tonic/gopher-social/internal/store/cache"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUpdateProfile(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patch := func(t *testing.T, mux http.Handler, body string) int {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodPatch, "/v1/users/me", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should update the profile", func(t *testing.T) {
+		code := patch(t, mux, `{"display_name":"Gopher","website":"https://go.dev","birthday":"2009-11-10","birthday_visibility":"public"}`)
+		checkResponseCode(t, http.StatusOK, code)
+	})
+
+	t.Run("should make the account private", func(t *testing.T) {
+		code := patch(t, mux, `{"is_private":true}`)
+		checkResponseCode(t, http.StatusOK, code)
+	})
+
+	t.Run("should allow clearing a field", func(t *testing.T) {
+		code := patch(t, mux, `{"website":"","birthday":""}`)
+		checkResponseCode(t, http.StatusOK, code)
+	})
+
+	t.Run("should reject a non-http website", func(t *testing.T) {
+		code := patch(t, mux, `{"website":"javascript:alert(1)"}`)
+		checkResponseCode(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("should reject a birthday in the future", func(t *testing.T) {
+		code := patch(t, mux, `{"birthday":"2999-01-01"}`)
+		checkResponseCode(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("should reject the name of the ghost user", func(t *testing.T) {
+		code := patch(t, mux, `{"display_name":"[Deleted]"}`)
+		checkResponseCode(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("should invalidate the cached user", func(t *testing.T) {
+		app := newTestApplication(t, config{redisCfg: redisConfig{enabled: true}})
+		mux := app.mount()
+
+		mockSessionCache := app.cacheStorage.Sessions.(*cache.MockSessionStore)
+		mockSessionCache.On("Get", "test-session").Return(nil, nil)
+		mockSessionCache.On("Set", mock.Anything).Return(nil)
+
+		mockCacheStore := app.cacheStorage.Users.(*cache.MockUserStore)
+		mockCacheStore.On("Get", int64(1)).Return(nil, nil)
+		mockCacheStore.On("Set", mock.Anything).Return(nil)
+		mockCacheStore.On("Delete", int64(1)).Return()
+
+		code := patch(t, mux, `{"bio":"Hello"}`)
+		checkResponseCode(t, http.StatusOK, code)
+
+		mockCacheStore.AssertCalled(t, "Delete", int64(1))
+	})
+}
+
+func TestTOTPStatusVisibility(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(t *testing.T, path string) string {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		return rr.Body.String()
+	}
+
+	t.Run("should show the user their own 2FA status", func(t *testing.T) {
+		if body := get(t, "/v1/users/me"); !strings.Contains(body, `"totp_enabled"`) {
+			t.Errorf("expected totp_enabled in %s", body)
+		}
+	})
+
+	t.Run("should hide the 2FA status of other users", func(t *testing.T) {
+		if body := get(t, "/v1/users/2"); strings.Contains(body, `"totp_enabled"`) {
+			t.Errorf("unexpected totp_enabled in %s", body)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+)
+
+var errUnknownReaction = errors.New("reaction is not one of the allowed emojis")
+
+type ReactionPayload struct {
+	Emoji string `json:"emoji" validate:"required" example:"👍"`
+}
+
+// ReactToPost godoc
+//
+//	@Summary		Reacts to a post
+//	@Description	Sets the authenticated user's reaction to a post, replacing their previous one
+//	@Tags			posts
+//	@Accept			json
+//	@Param			postID	path		int				true	"Post ID"
+//	@Param			payload	body		ReactionPayload	true	"Reaction"
+//	@Success		204		{string}	string			"Reaction set"
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error	"Blocked by or blocking the author"
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/reactions [put]
+func (app *application) reactToPostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromContext(r)
+
+	if !app.checkPostVisibility(w, r, post) {
+		return
+	}
+
+	app.react(w, r, store.PostReaction, post.ID)
+}
+
+// UnreactToPost godoc
+//
+//	@Summary		Removes a reaction from a post
+//	@Tags			posts
+//	@Param			postID	path		int		true	"Post ID"
+//	@Success		204		{string}	string	"Reaction removed"
+//	@Failure		404		{object}	error	"No reaction to remove"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/reactions [delete]
+func (app *application) unreactToPostHandler(w http.ResponseWriter, r *http.Request) {
+	app.unreact(w, r, store.PostReaction, getPostFromContext(r).ID)
+}
+
+// ReactToComment godoc
+//
+//	@Summary		Reacts to a comment
+//	@Description	Sets the authenticated user's reaction to a comment, replacing their previous one
+//	@Tags			comments
+//	@Accept			json
+//	@Param			postID		path		int				true	"Post ID"
+//	@Param			commentID	path		int				true	"Comment ID"
+//	@Param			payload		body		ReactionPayload	true	"Reaction"
+//	@Success		204			{string}	string			"Reaction set"
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error	"Blocked by or blocking the author"
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID}/reactions [put]
+func (app *application) reactToCommentHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.checkPostVisibility(w, r, getPostFromContext(r)) {
+		return
+	}
+
+	app.react(w, r, store.CommentReaction, getCommentFromContext(r).ID)
+}
+
+// UnreactToComment godoc
+//
+//	@Summary		Removes a reaction from a comment
+//	@Tags			comments
+//	@Param			postID		path		int		true	"Post ID"
+//	@Param			commentID	path		int		true	"Comment ID"
+//	@Success		204			{string}	string	"Reaction removed"
+//	@Failure		404			{object}	error	"No reaction to remove"
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID}/reactions [delete]
+func (app *application) unreactToCommentHandler(w http.ResponseWriter, r *http.Request) {
+	app.unreact(w, r, store.CommentReaction, getCommentFromContext(r).ID)
+}
+
+func (app *application) react(w http.ResponseWriter, r *http.Request, target store.ReactionTarget, targetID int64) {
+	var payload ReactionPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if !slices.Contains(app.config.reactions.emojis, payload.Emoji) {
+		app.badRequestResponse(w, r, errUnknownReaction)
+		return
+	}
+
+	err := app.store.Reactions.React(r.Context(), target, targetID, getUserFromContext(r).ID, payload.Emoji)
+	if err != nil {
+		switch err {
+		case store.ErrBlocked:
+			app.forbiddenResponse(w, r)
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrConflict:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) unreact(w http.ResponseWriter, r *http.Request, target store.ReactionTarget, targetID int64) {
+	if err := app.store.Reactions.Unreact(r.Context(), target, targetID, getUserFromContext(r).ID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+)
+
+func TestReactions(t *testing.T) {
+	app := newTestApplication(t, config{
+		reactions: reactionConfig{emojis: []string{"👍", "❤️"}},
+	})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(t *testing.T, method, path string, body io.Reader) int {
+		t.Helper()
+
+		req, err := http.NewRequest(method, path, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, mux).Code
+	}
+
+	for _, path := range []string{"/v1/posts/1/reactions", "/v1/posts/1/comments/3/reactions"} {
+		t.Run("should set and remove "+path, func(t *testing.T) {
+			checkResponseCode(t, http.StatusNoContent, do(t, http.MethodPut, path, strings.NewReader(`{"emoji":"❤️"}`)))
+			checkResponseCode(t, http.StatusNoContent, do(t, http.MethodDelete, path, nil))
+		})
+	}
+
+	t.Run("should reject an emoji outside the configured set", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, do(t, http.MethodPut, "/v1/posts/1/reactions", strings.NewReader(`{"emoji":"🦫"}`)))
+		checkResponseCode(t, http.StatusBadRequest, do(t, http.MethodPut, "/v1/posts/1/reactions", strings.NewReader(`{}`)))
+	})
+
+	t.Run("should not find a comment of another post", func(t *testing.T) {
+		checkResponseCode(t, http.StatusNotFound, do(t, http.MethodPut, "/v1/posts/2/comments/3/reactions", strings.NewReader(`{"emoji":"👍"}`)))
+	})
+
+	t.Run("should include the reaction counts in the post", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/posts/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var res struct {
+			Data store.Post `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+
+		if res.Data.ReactionCounts == nil {
+			t.Error("expected reaction counts")
+		}
+
+		if res.Data.MyReaction != nil {
+			t.Errorf("expected no reaction of the caller, got %q", *res.Data.MyReaction)
+		}
+	})
+}
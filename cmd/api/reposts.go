@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+)
+
+var errEditRepost = errors.New("reposts cannot be edited")
+
+// Repost godoc
+//
+//	@Summary		Reposts a post
+//	@Description	Shares a post with the authenticated user's followers. Reposting a repost shares its original. Posts of private accounts cannot be reposted
+//	@Tags			posts
+//	@Produce		json
+//	@Param			postID	path		int	true	"Post ID"
+//	@Success		201		{object}	store.Post
+//	@Failure		400		{object}	error	"The post is from a private account"
+//	@Failure		403		{object}	error	"Blocked by or blocking the author"
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error	"Already reposted"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/reposts [post]
+func (app *application) repostHandler(w http.ResponseWriter, r *http.Request) {
+	original := getPostFromContext(r)
+
+	if !app.checkPostVisibility(w, r, original) {
+		return
+	}
+
+	post := &store.Post{
+		UserID:     getUserFromContext(r).ID,
+		RepostOfID: &original.ID,
+		Tags:       []string{},
+	}
+
+	if err := app.store.Posts.Repost(r.Context(), post); err != nil {
+		switch err {
+		case store.ErrPrivatePost:
+			app.badRequestResponse(w, r, err)
+		case store.ErrBlocked:
+			app.forbiddenResponse(w, r)
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrConflict:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, post); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// Unrepost godoc
+//
+//	@Summary		Removes a repost
+//	@Description	Removes the authenticated user's repost of a post
+//	@Tags			posts
+//	@Param			postID	path		int		true	"Post ID"
+//	@Success		204		{string}	string	"Repost removed"
+//	@Failure		404		{object}	error	"Not reposted"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/reposts [delete]
+func (app *application) unrepostHandler(w http.ResponseWriter, r *http.Request) {
+	err := app.store.Posts.Unrepost(r.Context(), getUserFromContext(r).ID, getPostFromContext(r).ID)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
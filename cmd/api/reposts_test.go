@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReposts(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(t *testing.T, method, path string, body io.Reader) int {
+		t.Helper()
+
+		req, err := http.NewRequest(method, path, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should repost and remove the repost", func(t *testing.T) {
+		checkResponseCode(t, http.StatusCreated, do(t, http.MethodPost, "/v1/posts/1/reposts", nil))
+		checkResponseCode(t, http.StatusNoContent, do(t, http.MethodDelete, "/v1/posts/1/reposts", nil))
+	})
+
+	t.Run("should create a quote post", func(t *testing.T) {
+		body := `{"title":"Quoting","content":"Worth a read","quote_of_id":1}`
+		checkResponseCode(t, http.StatusCreated, do(t, http.MethodPost, "/v1/posts", strings.NewReader(body)))
+	})
+
+	t.Run("should reject an invalid quoted post ID", func(t *testing.T) {
+		body := `{"title":"Quoting","content":"Worth a read","quote_of_id":0}`
+		checkResponseCode(t, http.StatusBadRequest, do(t, http.MethodPost, "/v1/posts", strings.NewReader(body)))
+	})
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ana-tonic/gopher-social/internal/diff"
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+var errInvalidVersion = errors.New("version must be a non-negative integer")
+
+// RevisionDiff holds the word-level changes to the title and content of a
+// post between two of its versions.
+type RevisionDiff struct {
+	From    int         `json:"from"`
+	To      int         `json:"to"`
+	Title   []diff.Edit `json:"title"`
+	Content []diff.Edit `json:"content"`
+}
+
+type RestoreRevisionPayload struct {
+	// Version is the current version of the post, as last seen by the caller
+	Version *int `json:"version" validate:"required"`
+}
+
+// ListPostRevisions godoc
+//
+//	@Summary		Fetches the edit history of a post
+//	@Description	Lists the earlier versions of a post, most recent first. The current version is the post itself
+//	@Tags			posts
+//	@Produce		json
+//	@Param			postID	path		int	true	"Post ID"
+//	@Success		200		{array}		store.PostRevision
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/revisions [get]
+func (app *application) listRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromContext(r)
+
+	if !app.checkPostVisibility(w, r, post) {
+		return
+	}
+
+	revisions, err := app.store.Posts.GetRevisions(r.Context(), post.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, revisions); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DiffPostRevisions godoc
+//
+//	@Summary		Compares two versions of a post
+//	@Description	Returns the word-level changes to the title and content going from one version of a post to another
+//	@Tags			posts
+//	@Produce		json
+//	@Param			postID	path		int	true	"Post ID"
+//	@Param			from	query		int	true	"Version to compare from"
+//	@Param			to		query		int	false	"Version to compare to, the current one by default"
+//	@Success		200		{object}	RevisionDiff
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/revisions/diff [get]
+func (app *application) diffRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromContext(r)
+
+	if !app.checkPostVisibility(w, r, post) {
+		return
+	}
+
+	qs := r.URL.Query()
+
+	from, err := parseVersion(qs.Get("from"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	to := post.Version
+	if v := qs.Get("to"); v != "" {
+		if to, err = parseVersion(v); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	older, ok := app.getVersion(w, r, post, from)
+	if !ok {
+		return
+	}
+
+	newer, ok := app.getVersion(w, r, post, to)
+	if !ok {
+		return
+	}
+
+	d := RevisionDiff{
+		From:    from,
+		To:      to,
+		Title:   diff.Words(older.Title, newer.Title),
+		Content: diff.Words(older.Content, newer.Content),
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, d); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// RestorePostRevision godoc
+//
+//	@Summary		Restores an earlier version of a post
+//	@Description	Makes the title and content of an earlier version the current ones, as a new version. Fails if the post was edited since the given version
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID	path		int						true	"Post ID"
+//	@Param			version	path		int						true	"Version to restore"
+//	@Param			payload	body		RestoreRevisionPayload	true	"Current version of the post"
+//	@Success		200		{object}	store.Post
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error	"The post was edited in the meantime"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/revisions/{version}/restore [post]
+func (app *application) restoreRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromContext(r)
+
+	version, err := parseVersion(chi.URLParam(r, "version"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload RestoreRevisionPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	rev, err := app.store.Posts.GetRevision(r.Context(), post.ID, version)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	post.Title = rev.Title
+	post.Content = rev.Content
+	post.Version = *payload.Version
+
+	if err := app.store.Posts.Update(r.Context(), post); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getVersion returns version of post, either the post itself or one of its
+// revisions, responding with not found when there is no such version.
+func (app *application) getVersion(w http.ResponseWriter, r *http.Request, post *store.Post, version int) (*store.PostRevision, bool) {
+	if version == post.Version {
+		return &store.PostRevision{
+			PostID:    post.ID,
+			Version:   post.Version,
+			Title:     post.Title,
+			Content:   post.Content,
+			CreatedAt: post.UpdatedAt,
+		}, true
+	}
+
+	rev, err := app.store.Posts.GetRevision(r.Context(), post.ID, version)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return nil, false
+	}
+
+	return rev, true
+}
+
+func parseVersion(s string) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		return 0, errInvalidVersion
+	}
+
+	return v, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRevisions(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(t *testing.T, method, path string, body io.Reader) *httptest.ResponseRecorder {
+		t.Helper()
+
+		req, err := http.NewRequest(method, path, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, mux)
+	}
+
+	t.Run("should list the revisions", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, do(t, http.MethodGet, "/v1/posts/1/revisions", nil).Code)
+	})
+
+	t.Run("should diff a revision against the current version", func(t *testing.T) {
+		rr := do(t, http.MethodGet, "/v1/posts/1/revisions/diff?from=0", nil)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var resp struct {
+			Data RevisionDiff `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.Data.From != 0 || resp.Data.To != 1 {
+			t.Errorf("unexpected versions %d to %d", resp.Data.From, resp.Data.To)
+		}
+		if len(resp.Data.Content) == 0 || resp.Data.Content[0].Text != "the old content" {
+			t.Errorf("unexpected content diff %+v", resp.Data.Content)
+		}
+	})
+
+	t.Run("should not find an unknown version", func(t *testing.T) {
+		checkResponseCode(t, http.StatusNotFound, do(t, http.MethodGet, "/v1/posts/1/revisions/diff?from=7", nil).Code)
+	})
+
+	t.Run("should reject an invalid version", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, do(t, http.MethodGet, "/v1/posts/1/revisions/diff?from=-1", nil).Code)
+	})
+
+	t.Run("should restore a revision", func(t *testing.T) {
+		rr := do(t, http.MethodPost, "/v1/posts/1/revisions/0/restore", strings.NewReader(`{"version":1}`))
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should not restore over a newer edit", func(t *testing.T) {
+		rr := do(t, http.MethodPost, "/v1/posts/1/revisions/0/restore", strings.NewReader(`{"version":0}`))
+		checkResponseCode(t, http.StatusConflict, rr.Code)
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+)
+
+type UserSearchResults struct {
+	Users []store.UserSearchResult `json:"users"`
+	// NextCursor is passed as the cursor parameter to fetch the next page,
+	// it is empty on the last page
+	NextCursor string `json:"next_cursor"`
+}
+
+// SearchUsers godoc
+//
+//	@Summary		Searches users
+//	@Description	Finds users by username or display name. Closer matches come first, and users the caller follows or who are followed by people the caller follows are ranked higher
+//	@Tags			users
+//	@Produce		json
+//	@Param			q		query		string	true	"Search text"
+//	@Param			limit	query		int		false	"Number of users to return (default 20)"	minimum(1)	maximum(50)
+//	@Param			cursor	query		string	false	"Cursor returned with the previous page"
+//	@Success		200		{object}	UserSearchResults
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/search [get]
+func (app *application) searchUsersHandler(w http.ResponseWriter, r *http.Request) {
+	sq := store.UserSearchQuery{
+		Limit: 20,
+	}
+
+	sq, err := sq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(sq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	users, next, err := app.store.Users.Search(r.Context(), getUserFromContext(r).ID, sq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, UserSearchResults{Users: users, NextCursor: next}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
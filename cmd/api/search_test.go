@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+)
+
+func TestSearchUsers(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	search := func(t *testing.T, query string) int {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/search"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should search users", func(t *testing.T) {
+		checkResponseCode(t, http.StatusOK, search(t, "?q=gopher"))
+	})
+
+	t.Run("should accept a cursor from a previous page", func(t *testing.T) {
+		cursor := store.RankCursor{Rank: 0.4166667, ID: 7}.Encode()
+		checkResponseCode(t, http.StatusOK, search(t, "?q=gopher&limit=10&cursor="+cursor))
+	})
+
+	t.Run("should require a query", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, search(t, ""))
+		checkResponseCode(t, http.StatusBadRequest, search(t, "?q=%20%20"))
+	})
+
+	t.Run("should reject an invalid cursor", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, search(t, "?q=gopher&cursor=bm9wZQ"))
+	})
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type sessionKey string
+
+const sessionCtx sessionKey = "session"
+
+const (
+	// sessionTouchInterval limits how often a session's last seen time is
+	// written while it is in use
+	sessionTouchInterval = time.Minute
+	maxUserAgentLength   = 512
+)
+
+type SessionWithCurrent struct {
+	store.Session
+	Current bool `json:"current"`
+}
+
+// ListSessionsHandler godoc
+//
+//	@Summary		Lists sessions
+//	@Description	Lists the devices the authenticated user is logged in on, most recently used first
+//	@Tags			users
+//	@Produce		json
+//	@Success		200	{object}	[]SessionWithCurrent
+//	@Failure		403	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/sessions [get]
+func (app *application) listSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	sessions, err := app.store.Sessions.GetByUserID(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	currentID := getSessionIDFromContext(r)
+
+	res := make([]SessionWithCurrent, 0, len(sessions))
+	for _, session := range sessions {
+		res = append(res, SessionWithCurrent{
+			Session: session,
+			Current: session.ID == currentID,
+		})
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, res); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// RevokeSessionHandler godoc
+//
+//	@Summary		Revokes a session
+//	@Description	Logs the authenticated user out of one device, invalidating its access and refresh tokens
+//	@Tags			users
+//	@Param			sessionID	path		string	true	"Session ID"
+//	@Success		204			{string}	string	"Session revoked"
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/sessions/{sessionID} [delete]
+func (app *application) revokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+	if _, err := uuid.Parse(sessionID); err != nil {
+		app.notFoundResponse(w, r, store.ErrNotFound)
+		return
+	}
+
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	if err := app.store.Sessions.RevokeForUser(ctx, sessionID, user.ID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.forgetSession(ctx, sessionID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getSession returns the session, going through the cache when Redis is
+// enabled.
+func (app *application) getSession(ctx context.Context, id string) (*store.Session, error) {
+	if !app.config.redisCfg.enabled {
+		return app.store.Sessions.GetByID(ctx, id)
+	}
+
+	session, err := app.cacheStorage.Sessions.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if session == nil {
+		session, err = app.store.Sessions.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = app.cacheStorage.Sessions.Set(ctx, session); err != nil {
+			return nil, err
+		}
+	}
+
+	return session, nil
+}
+
+// forgetSession drops a revoked session from the cache so its access tokens
+// stop working right away.
+func (app *application) forgetSession(ctx context.Context, id string) {
+	if !app.config.redisCfg.enabled {
+		return
+	}
+
+	if err := app.cacheStorage.Sessions.Delete(ctx, id); err != nil {
+		app.logger.Errorw("error removing session from cache", "session_id", id, "error", err)
+	}
+}
+
+// forgetUserSessions drops every session of the user from the cache after
+// they were revoked together.
+func (app *application) forgetUserSessions(ctx context.Context, userID int64) {
+	if !app.config.redisCfg.enabled {
+		return
+	}
+
+	if err := app.cacheStorage.Sessions.DeleteForUser(ctx, userID); err != nil {
+		app.logger.Errorw("error removing sessions from cache", "user_id", userID, "error", err)
+	}
+}
+
+// touchSession updates the session's last seen time and IP, at most once per
+// sessionTouchInterval.
+func (app *application) touchSession(ctx context.Context, session *store.Session, r *http.Request) {
+	ip := clientIP(r)
+
+	lastSeen, err := time.Parse(time.RFC3339, session.LastSeenAt)
+	if err == nil && time.Since(lastSeen) < sessionTouchInterval && session.IP == ip {
+		return
+	}
+
+	if err := app.store.Sessions.Touch(ctx, session, ip); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			// revoked since it was cached
+			app.forgetSession(ctx, session.ID)
+		default:
+			app.logger.Errorw("error updating session activity", "session_id", session.ID, "error", err)
+		}
+		return
+	}
+
+	if app.config.redisCfg.enabled {
+		if err := app.cacheStorage.Sessions.Set(ctx, session); err != nil {
+			app.logger.Errorw("error caching session", "session_id", session.ID, "error", err)
+		}
+	}
+}
+
+// clientIP returns the client address, as set by middleware.RealIP.
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return ip
+}
+
+func userAgent(r *http.Request) string {
+	ua := r.UserAgent()
+	if len(ua) > maxUserAgentLength {
+		ua = ua[:maxUserAgentLength]
+	}
+
+	return ua
+}
+
+func getSessionIDFromContext(r *http.Request) string {
+	id, _ := r.Context().Value(sessionCtx).(string)
+	return id
+}
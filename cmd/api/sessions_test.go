@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/ana-tonic/gopher-social/internal/store/cache"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSessions(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should list sessions and mark the current one", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/me/sessions", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var body struct {
+			Data []SessionWithCurrent `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(body.Data) != 1 || !body.Data[0].Current {
+			t.Errorf("expected the current session to be listed, got %+v", body.Data)
+		}
+	})
+
+	t.Run("should revoke a session", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodDelete, "/v1/users/me/sessions/"+uuid.New().String(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("should return 404 for a malformed session ID", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodDelete, "/v1/users/me/sessions/nope", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should not let an API key manage sessions", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/me/sessions", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "ApiKey "+store.MockAPIKeyToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("should reject a session revoked in the cache", func(t *testing.T) {
+		app := newTestApplication(t, config{redisCfg: redisConfig{enabled: true}})
+		mux := app.mount()
+
+		revokedAt := "2024-01-01T00:00:00Z"
+		mockSessionCache := app.cacheStorage.Sessions.(*cache.MockSessionStore)
+		mockSessionCache.On("Get", "test-session").Return(&store.Session{ID: "test-session", UserID: 1, RevokedAt: &revokedAt}, nil)
+		mockSessionCache.On("Set", mock.Anything).Return(nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/me/sessions", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should drop a session revoked since it was cached", func(t *testing.T) {
+		app := newTestApplication(t, config{redisCfg: redisConfig{enabled: true}})
+		app.store.Sessions = &revokedSessionStore{}
+		mux := app.mount()
+
+		mockUserCache := app.cacheStorage.Users.(*cache.MockUserStore)
+		mockUserCache.On("Get", int64(1)).Return(nil, nil)
+		mockUserCache.On("Set", mock.Anything).Return(nil)
+
+		mockSessionCache := app.cacheStorage.Sessions.(*cache.MockSessionStore)
+		mockSessionCache.On("Get", "test-session").Return(&store.Session{ID: "test-session", UserID: 1}, nil)
+		mockSessionCache.On("Delete", "test-session").Return(nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/me/sessions", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		executeRequest(req, mux)
+
+		mockSessionCache.AssertCalled(t, "Delete", "test-session")
+		mockSessionCache.AssertNotCalled(t, "Set", mock.Anything)
+	})
+}
+
+// revokedSessionStore behaves as if every session was revoked in the
+// database.
+type revokedSessionStore struct {
+	store.MockSessionStore
+}
+
+func (m *revokedSessionStore) Touch(ctx context.Context, session *store.Session, ip string) error {
+	return store.ErrNotFound
+}
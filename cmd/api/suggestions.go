@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+)
+
+// maxSuggestions is the number of suggestions computed and cached per user,
+// the limit parameter only trims this list
+const maxSuggestions = 50
+
+var errInvalidLimit = errors.New("limit must be between 1 and 50")
+
+// GetSuggestions godoc
+//
+//	@Summary		Suggests users to follow
+//	@Description	Suggests accounts followed by the users the caller follows and accounts posting with the caller's most used tags
+//	@Tags			users
+//	@Produce		json
+//	@Param			limit	query		int	false	"Number of users to return (default 20)"	minimum(1)	maximum(50)
+//	@Success		200		{object}	[]store.Suggestion
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/suggestions [get]
+func (app *application) getSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxSuggestions {
+			app.badRequestResponse(w, r, errInvalidLimit)
+			return
+		}
+		limit = n
+	}
+
+	suggestions, err := app.getSuggestions(r.Context(), getUserFromContext(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, suggestions); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) getSuggestions(ctx context.Context, userID int64) ([]store.Suggestion, error) {
+	if !app.config.redisCfg.enabled {
+		return app.store.Followers.GetSuggestions(ctx, userID, maxSuggestions)
+	}
+
+	suggestions, err := app.cacheStorage.Suggestions.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if suggestions == nil {
+		suggestions, err = app.store.Followers.GetSuggestions(ctx, userID, maxSuggestions)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := app.cacheStorage.Suggestions.Set(ctx, userID, suggestions); err != nil {
+			return nil, err
+		}
+	}
+
+	return suggestions, nil
+}
+
+// forgetSuggestions drops the cached suggestions of the users, so someone who
+// was just followed, blocked or muted is not suggested again.
+func (app *application) forgetSuggestions(ctx context.Context, userIDs ...int64) {
+	if !app.config.redisCfg.enabled {
+		return
+	}
+
+	for _, userID := range userIDs {
+		if err := app.cacheStorage.Suggestions.Delete(ctx, userID); err != nil {
+			app.logger.Errorw("error deleting cached suggestions", "user_id", userID, "error", err)
+		}
+	}
+}
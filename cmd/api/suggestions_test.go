@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/ana-tonic/gopher-social/internal/store/cache"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetSuggestions(t *testing.T) {
+	get := func(t *testing.T, app *application, query string) int {
+		t.Helper()
+
+		testToken, err := app.authenticator.GenerateToken(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/me/suggestions"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, app.mount()).Code
+	}
+
+	t.Run("should return suggestions", func(t *testing.T) {
+		app := newTestApplication(t, config{})
+		checkResponseCode(t, http.StatusOK, get(t, app, "?limit=5"))
+	})
+
+	t.Run("should reject an out of range limit", func(t *testing.T) {
+		app := newTestApplication(t, config{})
+		checkResponseCode(t, http.StatusBadRequest, get(t, app, "?limit=51"))
+	})
+
+	t.Run("should cache suggestions per user", func(t *testing.T) {
+		app := newTestApplication(t, config{redisCfg: redisConfig{enabled: true}})
+
+		mockSessionCache := app.cacheStorage.Sessions.(*cache.MockSessionStore)
+		mockSessionCache.On("Get", "test-session").Return(nil, nil)
+		mockSessionCache.On("Set", mock.Anything).Return(nil)
+
+		mockUserCache := app.cacheStorage.Users.(*cache.MockUserStore)
+		mockUserCache.On("Get", int64(1)).Return(nil, nil)
+		mockUserCache.On("Set", mock.Anything).Return(nil)
+
+		mockSuggestionCache := app.cacheStorage.Suggestions.(*cache.MockSuggestionStore)
+		mockSuggestionCache.On("Get", int64(1)).Return(nil, nil).Once()
+		mockSuggestionCache.On("Set", int64(1), mock.Anything).Return(nil).Once()
+		mockSuggestionCache.On("Get", int64(1)).Return([]store.Suggestion{}, nil).Once()
+
+		checkResponseCode(t, http.StatusOK, get(t, app, ""))
+		checkResponseCode(t, http.StatusOK, get(t, app, ""))
+
+		mockSuggestionCache.AssertNumberOfCalls(t, "Get", 2)
+		mockSuggestionCache.AssertNumberOfCalls(t, "Set", 1)
+	})
+}
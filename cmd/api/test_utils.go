@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/ana-tonic/gopher-social/internal/auth"
+	"github.com/ana-tonic/gopher-social/internal/media"
 	"github.com/ana-tonic/gopher-social/internal/ratelimiter"
 	"github.com/ana-tonic/gopher-social/internal/store"
 	"github.com/ana-tonic/gopher-social/internal/store/cache"
@@ -21,6 +22,11 @@ func newTestApplication(t *testing.T, cfg config) *application {
 
 	testAuth := &auth.TestAuthenticator{}
 
+	blobStore, err := media.NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	// Rate limiter
 	rateLimiter := ratelimiter.NewFixedWindowRateLimiter(
 		cfg.rateLimiter.RequestsPerTimeFrame,
@@ -34,6 +40,16 @@ func newTestApplication(t *testing.T, cfg config) *application {
 		authenticator: testAuth,
 		config:        cfg,
 		rateLimiter:   rateLimiter,
+		loginGuard:    ratelimiter.NewLoginGuard(ratelimiter.NewMemoryAttemptStore(), cfg.loginGuard),
+		invitationLimiter: ratelimiter.NewFixedWindowRateLimiter(
+			cfg.invitation.resendLimiter.RequestsPerTimeFrame,
+			cfg.invitation.resendLimiter.TimeFrame,
+		),
+		resetLimiter: ratelimiter.NewFixedWindowRateLimiter(
+			cfg.mail.resetLimiter.RequestsPerTimeFrame,
+			cfg.mail.resetLimiter.TimeFrame,
+		),
+		blobStore: blobStore,
 	}
 }
 
@@ -3,6 +3,7 @@ package main
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/ana-tonic/gopher-social/internal/store"
 	"github.com/go-chi/chi/v5"
@@ -13,11 +14,17 @@ type userKey string
 const userCtx userKey = "user"
 
 type CreateUserPayload struct {
-	Username string `json:"username" validate:"required,max=255"`
+	Username string `json:"username" validate:"required,max=255,notreserved"`
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=8"`
 }
 
+// isReservedUsername reports whether name is the one of the ghost user that
+// the posts and comments of purged accounts are moved to.
+func isReservedUsername(name string) bool {
+	return strings.EqualFold(strings.TrimSpace(name), store.DeletedUsername)
+}
+
 // GetUser godoc
 //
 //	@Summary		Fetches a user profile
@@ -27,7 +34,7 @@ type CreateUserPayload struct {
 //	@Produce		json
 //
 //	@Param			id	path		int	true	"User ID"
-//	@Success		200	{object}	store.User
+//	@Success		200	{object}	UserWithFollowStatus
 //	@Failure		400	{object}	error
 //	@Failure		404	{object}	error
 //	@Failure		500	{object}	error
@@ -40,7 +47,10 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := app.getUser(r.Context(), userID)
+	ctx := r.Context()
+	viewer := getUserFromContext(r)
+
+	user, err := app.getUser(ctx, userID)
 	if err != nil {
 		switch err {
 		case store.ErrNotFound:
@@ -51,7 +61,18 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, user); err != nil {
+	followed, err := app.store.Followers.IsFollowing(ctx, viewer.ID, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	res := UserWithFollowStatus{
+		User:         profileFor(user, viewer),
+		FollowedByMe: followed,
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, res); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
@@ -59,15 +80,18 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 // FollowUser godoc
 //
 //	@Summary		Follows a user
-//	@Description	Follows a user by ID
+//	@Description	Follows a user by ID. Following a private account files a follow request the owner has to approve
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
 //
 //	@Param			userID	path		int		true	"User ID"
+//	@Success		202		{string}	string	"Follow request sent"
 //	@Success		204		{string}	string	"User followed"
 //	@Failure		400		{object}	error	"User payload missing"
+//	@Failure		403		{object}	error	"Either user has blocked the other"
 //	@Failure		404		{object}	error	"User not found"
+//	@Failure		409		{object}	error	"User already followed"
 //	@Security		ApiKeyAuth
 //	@Router			/users/{userID}/follow [put]
 func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -80,16 +104,32 @@ func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request
 
 	ctx := r.Context()
 
-	if err := app.store.Followers.Follow(ctx, followerUser.ID, followedID); err != nil {
+	pending, err := app.store.Followers.Follow(ctx, followerUser.ID, followedID)
+	if err != nil {
 		switch err {
 		case store.ErrConflict:
 			app.conflictResponse(w, r, err)
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrBlocked:
+			app.forbiddenResponse(w, r)
 		default:
 			app.internalServerError(w, r, err)
 		}
 		return
 	}
 
+	app.forgetSuggestions(ctx, followerUser.ID)
+
+	if pending {
+		if err := app.jsonResponse(w, http.StatusAccepted, nil); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.forgetFollowCounts(ctx, followerUser.ID, followedID)
+
 	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -98,7 +138,7 @@ func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request
 }
 
 // @Summary		Unfollows a user
-// @Description	Unfollows a user by ID
+// @Description	Unfollows a user by ID, or withdraws a pending follow request
 // @Tags			users
 // @Accept			json
 // @Produce		json
@@ -124,6 +164,8 @@ func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	app.forgetFollowCounts(ctx, followedUser.ID, unfollowedID)
+
 	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
 		app.internalServerError(w, r, err)
 		return
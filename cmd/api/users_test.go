@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/ana-tonic/gopher-social/internal/auth"
 	"github.com/ana-tonic/gopher-social/internal/store/cache"
 	"github.com/stretchr/testify/mock"
 )
@@ -18,6 +19,11 @@ func TestGetUser(t *testing.T) {
 	app := newTestApplication(t, withRedis)
 	mux := app.mount()
 
+	// sessions are looked up through the cache as well
+	mockSessionCache := app.cacheStorage.Sessions.(*cache.MockSessionStore)
+	mockSessionCache.On("Get", "test-session").Return(nil, nil)
+	mockSessionCache.On("Set", mock.Anything).Return(nil)
+
 	testToken, err := app.authenticator.GenerateToken(nil)
 	if err != nil {
 		t.Fatal(err)
@@ -103,3 +109,19 @@ func TestGetUser(t *testing.T) {
 		mockCacheStore.Calls = nil // Reset mock expectations
 	})
 }
+
+func TestReservedUsername(t *testing.T) {
+	t.Run("should not register the name of the ghost user", func(t *testing.T) {
+		payload := RegisterUserPayload{Username: " [deleted] ", Email: "gopher@example.com", Password: "password"}
+
+		if err := Validate.Struct(payload); err == nil {
+			t.Error("expected the username to be rejected")
+		}
+	})
+
+	t.Run("should not derive the name of the ghost user from a provider", func(t *testing.T) {
+		if name := oidcUsername(&auth.OIDCClaims{PreferredUsername: "[deleted]"}); isReservedUsername(name) {
+			t.Errorf("expected a free username, got %q", name)
+		}
+	})
+}
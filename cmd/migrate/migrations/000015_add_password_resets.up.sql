@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS password_resets (
+    token bytea PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expiry TIMESTAMP(0) WITH TIME ZONE NOT NULL
+);
@@ -0,0 +1,4 @@
+ALTER TABLE sessions
+    ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS ip VARCHAR(45) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW();
@@ -0,0 +1,7 @@
+ALTER TABLE users
+ADD COLUMN display_name VARCHAR(50) NOT NULL DEFAULT '',
+ADD COLUMN bio VARCHAR(160) NOT NULL DEFAULT '',
+ADD COLUMN website VARCHAR(255) NOT NULL DEFAULT '',
+ADD COLUMN location VARCHAR(100) NOT NULL DEFAULT '',
+ADD COLUMN birthday DATE,
+ADD COLUMN birthday_visibility VARCHAR(10) NOT NULL DEFAULT 'private';
@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_media_id;
+
+DROP TABLE IF EXISTS post_media;
+
+DROP TABLE IF EXISTS media;
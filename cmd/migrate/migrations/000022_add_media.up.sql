@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS media (
+    id bigserial PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key TEXT NOT NULL UNIQUE,
+    thumbnail_key TEXT NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    size BIGINT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_user_id ON media (user_id);
+
+CREATE TABLE IF NOT EXISTS post_media (
+    post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    media_id BIGINT NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    PRIMARY KEY (post_id, media_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_media_media_id ON post_media (media_id);
+
+ALTER TABLE users
+ADD COLUMN avatar_media_id BIGINT REFERENCES media(id) ON DELETE SET NULL;
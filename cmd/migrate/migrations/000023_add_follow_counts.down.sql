@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_followers_follower_id_created_at;
+
+DROP INDEX IF EXISTS idx_followers_user_id_created_at;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS following_count,
+DROP COLUMN IF EXISTS follower_count;
@@ -0,0 +1,12 @@
+ALTER TABLE users
+ADD COLUMN follower_count BIGINT NOT NULL DEFAULT 0,
+ADD COLUMN following_count BIGINT NOT NULL DEFAULT 0;
+
+-- followers.user_id is the user doing the following and follower_id the one being followed
+UPDATE users u SET
+    following_count = (SELECT COUNT(*) FROM followers f WHERE f.user_id = u.id),
+    follower_count = (SELECT COUNT(*) FROM followers f WHERE f.follower_id = u.id);
+
+CREATE INDEX IF NOT EXISTS idx_followers_user_id_created_at ON followers (user_id, created_at DESC, follower_id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_followers_follower_id_created_at ON followers (follower_id, created_at DESC, user_id DESC);
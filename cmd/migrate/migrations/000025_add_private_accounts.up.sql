@@ -0,0 +1,11 @@
+ALTER TABLE users
+ADD COLUMN is_private BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS follow_requests (
+    requester_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (requester_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_follow_requests_user_id_created_at ON follow_requests (user_id, created_at DESC, requester_id DESC);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_display_name_trgm;
+
+DROP INDEX IF EXISTS idx_users_username_trgm;
@@ -0,0 +1,14 @@
+DELETE FROM comments WHERE user_id = (SELECT id FROM users WHERE is_ghost);
+
+DELETE FROM posts WHERE user_id = (SELECT id FROM users WHERE is_ghost);
+
+DELETE FROM users WHERE is_ghost;
+
+DROP INDEX IF EXISTS idx_users_ghost;
+
+DROP INDEX IF EXISTS idx_users_delete_after;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS is_ghost,
+DROP COLUMN IF EXISTS delete_after,
+DROP COLUMN IF EXISTS deactivated_at;
@@ -0,0 +1,14 @@
+ALTER TABLE users
+ADD COLUMN deactivated_at TIMESTAMP(0) WITH TIME ZONE,
+ADD COLUMN delete_after TIMESTAMP(0) WITH TIME ZONE,
+ADD COLUMN is_ghost BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_users_delete_after ON users (delete_after) WHERE delete_after IS NOT NULL;
+
+-- there is a single ghost user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_ghost ON users (is_ghost) WHERE is_ghost;
+
+-- anonymized posts and comments of purged accounts are moved to this user,
+-- the migration fails rather than go without it if the username is taken
+INSERT INTO users (username, email, password, is_active, deactivated_at, is_ghost)
+VALUES ('[deleted]', 'deleted@deleted.invalid', '', false, NOW(), true);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS data_exports (
+    id bigserial PRIMARY KEY,
+    -- kept when the user is purged so the sweeper still removes the archive
+    user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    key TEXT,
+    token bytea UNIQUE,
+    expiry TIMESTAMP(0) WITH TIME ZONE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP(0) WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports (user_id);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS comment_reactions;
+
+DROP TABLE IF EXISTS post_reactions;
+
+ALTER TABLE comments
+DROP COLUMN IF EXISTS reaction_counts;
+
+ALTER TABLE posts
+DROP COLUMN IF EXISTS reaction_counts;
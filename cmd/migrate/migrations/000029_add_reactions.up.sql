@@ -0,0 +1,25 @@
+ALTER TABLE posts
+ADD COLUMN reaction_counts JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE comments
+ADD COLUMN reaction_counts JSONB NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS post_reactions (
+    post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    emoji TEXT NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (post_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_reactions_user_id ON post_reactions (user_id);
+
+CREATE TABLE IF NOT EXISTS comment_reactions (
+    comment_id BIGINT NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    emoji TEXT NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (comment_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_reactions_user_id ON comment_reactions (user_id);
@@ -0,0 +1,11 @@
+-- a repost is a post without content of its own and goes away with the
+-- original, a quote post keeps its commentary and loses the reference
+ALTER TABLE posts
+ADD COLUMN repost_of_id BIGINT REFERENCES posts(id) ON DELETE CASCADE,
+ADD COLUMN quote_of_id BIGINT REFERENCES posts(id) ON DELETE SET NULL,
+ADD COLUMN is_quote BOOLEAN NOT NULL DEFAULT false,
+ADD CONSTRAINT posts_repost_or_quote CHECK (repost_of_id IS NULL OR NOT is_quote);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_user_id_repost_of_id ON posts (user_id, repost_of_id) WHERE repost_of_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_quote_of_id ON posts (quote_of_id) WHERE quote_of_id IS NOT NULL;
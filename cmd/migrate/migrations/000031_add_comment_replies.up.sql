@@ -0,0 +1,7 @@
+ALTER TABLE comments
+ADD COLUMN parent_id BIGINT REFERENCES comments(id) ON DELETE CASCADE,
+ADD COLUMN depth INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_comments_post_id_created_at ON comments (post_id, created_at DESC, id DESC) WHERE parent_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id_created_at ON comments (parent_id, created_at, id) WHERE parent_id IS NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE comments
+ADD COLUMN edited_at TIMESTAMP(0) WITH TIME ZONE;
@@ -0,0 +1,9 @@
+-- the versions of a post replaced by edits, the current one stays on posts
+CREATE TABLE IF NOT EXISTS post_revisions (
+    post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (post_id, version)
+);
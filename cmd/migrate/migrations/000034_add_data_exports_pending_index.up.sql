@@ -0,0 +1,9 @@
+-- only the latest of several exports still being built is kept, a user can
+-- have one at a time
+DELETE FROM data_exports e
+WHERE e.completed_at IS NULL AND EXISTS (
+    SELECT 1 FROM data_exports n
+    WHERE n.user_id = e.user_id AND n.completed_at IS NULL AND n.id > e.id
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_exports_pending ON data_exports (user_id) WHERE completed_at IS NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE comments
+DROP CONSTRAINT IF EXISTS fk_comments_post;
@@ -0,0 +1,8 @@
+-- Comments used to outlive their post: deleting a post left its comments
+-- behind, unreachable by any endpoint. They are removed for good here, the
+-- down migration does not bring them back.
+DELETE FROM comments c WHERE NOT EXISTS (SELECT 1 FROM posts p WHERE p.id = c.post_id);
+
+-- from now on the comments of a deleted post, repost included, go with it
+ALTER TABLE comments
+ADD CONSTRAINT fk_comments_post FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE;
@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeySetProvider is implemented by authenticators whose verification keys can
+// be published so other services are able to validate tokens on their own.
+type KeySetProvider interface {
+	JWKS() JWKSet
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    crypto.PublicKey
+	jwk    JWK
+}
+
+// KeyPairAuthenticator signs tokens with an RSA (RS256) or Ed25519 (EdDSA)
+// private key and accepts tokens signed by any of its verification keys, so
+// a new signing key can be rolled out while tokens signed by the previous
+// one are still valid.
+type KeyPairAuthenticator struct {
+	signingKey crypto.Signer
+	signingKID string
+	method     jwt.SigningMethod
+	keys       map[string]verificationKey
+	aud        string
+	iss        string
+}
+
+// NewKeyPairAuthenticator loads the PEM encoded private signing key and any
+// additional PEM encoded keys (public or private) that should still be
+// accepted when validating tokens.
+func NewKeyPairAuthenticator(signingKeyPath string, verificationKeyPaths []string, aud, iss string) (*KeyPairAuthenticator, error) {
+	data, err := os.ReadFile(signingKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("signing key %s: %w", signingKeyPath, err)
+	}
+
+	signingKey, err := newVerificationKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	a := &KeyPairAuthenticator{
+		signingKey: signer,
+		signingKID: signingKey.jwk.Kid,
+		method:     signingKey.method,
+		keys:       map[string]verificationKey{signingKey.jwk.Kid: signingKey},
+		aud:        aud,
+		iss:        iss,
+	}
+
+	for _, path := range verificationKeyPaths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		pub, err := parsePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("verification key %s: %w", path, err)
+		}
+
+		key, err := newVerificationKey(pub)
+		if err != nil {
+			return nil, err
+		}
+
+		a.keys[key.jwk.Kid] = key
+	}
+
+	return a, nil
+}
+
+func (a *KeyPairAuthenticator) GenerateToken(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(a.method, claims)
+	token.Header["kid"] = a.signingKID
+
+	return token.SignedString(a.signingKey)
+}
+
+func (a *KeyPairAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
+	return jwt.Parse(token, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		key, ok := a.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id: %q", kid)
+		}
+
+		if t.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+
+		return key.key, nil
+	},
+		jwt.WithExpirationRequired(),
+		jwt.WithAudience(a.aud),
+		jwt.WithIssuer(a.iss),
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+	)
+}
+
+func (a *KeyPairAuthenticator) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(a.keys))}
+
+	// the current signing key goes first
+	set.Keys = append(set.Keys, a.keys[a.signingKID].jwk)
+	for kid, key := range a.keys {
+		if kid != a.signingKID {
+			set.Keys = append(set.Keys, key.jwk)
+		}
+	}
+
+	return set
+}
+
+func newVerificationKey(pub crypto.PublicKey) (verificationKey, error) {
+	enc := base64.RawURLEncoding
+
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		jwk := JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: jwt.SigningMethodRS256.Alg(),
+			N:   enc.EncodeToString(k.N.Bytes()),
+			E:   enc.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}
+		// RFC 7638 thumbprint: required members in lexicographic order
+		jwk.Kid = thumbprint(map[string]string{"e": jwk.E, "kty": jwk.Kty, "n": jwk.N})
+
+		return verificationKey{method: jwt.SigningMethodRS256, key: k, jwk: jwk}, nil
+	case ed25519.PublicKey:
+		jwk := JWK{
+			Kty: "OKP",
+			Use: "sig",
+			Alg: jwt.SigningMethodEdDSA.Alg(),
+			Crv: "Ed25519",
+			X:   enc.EncodeToString(k),
+		}
+		jwk.Kid = thumbprint(map[string]string{"crv": jwk.Crv, "kty": jwk.Kty, "x": jwk.X})
+
+		return verificationKey{method: jwt.SigningMethodEdDSA, key: k, jwk: jwk}, nil
+	default:
+		return verificationKey{}, fmt.Errorf("unsupported key type %T", pub)
+	}
+}
+
+func thumbprint(members map[string]string) string {
+	// encoding/json sorts map keys, which gives the canonical form
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		switch k := key.(type) {
+		case *rsa.PrivateKey:
+			return k, nil
+		case ed25519.PrivateKey:
+			return k, nil
+		default:
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		// a retired private key is still usable for verification
+		signer, err := parsePrivateKey(data)
+		if err != nil {
+			return nil, err
+		}
+
+		return signer.Public(), nil
+	}
+}
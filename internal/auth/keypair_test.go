@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func writeKey(t *testing.T, key any) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func testClaimsFor(aud string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": 1,
+		"aud": aud,
+		"iss": aud,
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+}
+
+func TestKeyPairAuthenticator(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaPath := writeKey(t, rsaKey)
+	edPath := writeKey(t, edKey)
+
+	t.Run("should sign and validate with a kid header", func(t *testing.T) {
+		for _, path := range []string{rsaPath, edPath} {
+			a, err := NewKeyPairAuthenticator(path, nil, "aud", "aud")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := a.GenerateToken(testClaimsFor("aud"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := a.ValidateToken(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if parsed.Header["kid"] != a.signingKID {
+				t.Errorf("expected kid %q, got %v", a.signingKID, parsed.Header["kid"])
+			}
+		}
+	})
+
+	t.Run("should accept tokens signed by a rotated out key", func(t *testing.T) {
+		old, err := NewKeyPairAuthenticator(rsaPath, nil, "aud", "aud")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		token, err := old.GenerateToken(testClaimsFor("aud"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rotated, err := NewKeyPairAuthenticator(edPath, []string{rsaPath}, "aud", "aud")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := rotated.ValidateToken(token); err != nil {
+			t.Fatalf("expected token signed by the previous key to be valid: %v", err)
+		}
+
+		keys := rotated.JWKS().Keys
+		if len(keys) != 2 || keys[0].Kid != rotated.signingKID {
+			t.Errorf("expected 2 keys with the signing key first, got %+v", keys)
+		}
+	})
+
+	t.Run("should reject tokens signed by an unknown key", func(t *testing.T) {
+		signer, err := NewKeyPairAuthenticator(rsaPath, nil, "aud", "aud")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		token, err := signer.GenerateToken(testClaimsFor("aud"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		verifier, err := NewKeyPairAuthenticator(edPath, nil, "aud", "aud")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := verifier.ValidateToken(token); err == nil {
+			t.Error("expected an error for an unknown kid")
+		}
+	})
+}
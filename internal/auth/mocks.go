@@ -14,6 +14,7 @@ var testClaims = jwt.MapClaims{
 	"aud": "test-aud",
 	"iss": "test-iss",
 	"sub": int64(1),
+	"sid": "test-session",
 	"exp": time.Now().Add(time.Hour).Unix(),
 }
 
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TestOIDCServer is a stand-in OpenID Connect provider for tests. Its
+// authorization endpoint approves every request right away and redirects back
+// with a code for the configured Subject.
+type TestOIDCServer struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	mu     sync.Mutex
+	claims map[string]any
+	codes  map[string]testOIDCGrant
+	key    ed25519.PrivateKey
+	kid    string
+}
+
+type testOIDCGrant struct {
+	challenge string
+	nonce     string
+	redirect  string
+}
+
+func NewTestOIDCServer() *TestOIDCServer {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	vk, err := newVerificationKey(pub)
+	if err != nil {
+		panic(err)
+	}
+
+	s := &TestOIDCServer{
+		ClientID:     "test-client",
+		ClientSecret: "test-secret",
+		claims: map[string]any{
+			"sub":            "test-subject",
+			"email":          "gopher@example.com",
+			"email_verified": true,
+			"name":           "Test Gopher",
+		},
+		codes: make(map[string]testOIDCGrant),
+		key:   key,
+		kid:   vk.jwk.Kid,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(oidcDiscovery{
+			Issuer:                s.URL,
+			AuthorizationEndpoint: s.URL + "/authorize",
+			TokenEndpoint:         s.URL + "/token",
+			JWKSURI:               s.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(JWKSet{Keys: []JWK{vk.jwk}})
+	})
+	mux.HandleFunc("GET /authorize", s.authorize)
+	mux.HandleFunc("POST /token", s.token)
+
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// SetClaims overrides ID token claims for the following logins.
+func (s *TestOIDCServer) SetClaims(claims map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, v := range claims {
+		s.claims[k] = v
+	}
+}
+
+func (s *TestOIDCServer) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != s.ClientID || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	code, err := randomString(16)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.mu.Lock()
+	s.codes[code] = testOIDCGrant{
+		challenge: q.Get("code_challenge"),
+		nonce:     q.Get("nonce"),
+		redirect:  q.Get("redirect_uri"),
+	}
+	s.mu.Unlock()
+
+	v := url.Values{}
+	v.Set("code", code)
+	v.Set("state", q.Get("state"))
+
+	http.Redirect(w, r, q.Get("redirect_uri")+"?"+v.Encode(), http.StatusFound)
+}
+
+func (s *TestOIDCServer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	grant, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	claims := jwt.MapClaims{}
+	for k, v := range s.claims {
+		claims[k] = v
+	}
+	s.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	if !ok ||
+		challenge != grant.challenge ||
+		r.PostForm.Get("redirect_uri") != grant.redirect ||
+		r.PostForm.Get("client_id") != s.ClientID ||
+		r.PostForm.Get("client_secret") != s.ClientSecret {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	claims["iss"] = s.URL
+	claims["aud"] = s.ClientID
+	claims["nonce"] = grant.nonce
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(time.Minute).Unix()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = s.kid
+
+	idToken, err := token.SignedString(s.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "test-access-token",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
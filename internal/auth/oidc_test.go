@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestOIDCProvider(t *testing.T) {
+	srv := NewTestOIDCServer()
+	defer srv.Close()
+
+	ctx := context.Background()
+	provider := NewOIDCProvider(OIDCConfig{
+		Issuer:       srv.URL,
+		ClientID:     srv.ClientID,
+		ClientSecret: srv.ClientSecret,
+		RedirectURL:  "http://localhost:8080/callback",
+	}, nil)
+
+	noRedirect := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	authorize := func(t *testing.T, challenge string) url.Values {
+		t.Helper()
+
+		state, nonce, err := GenerateOIDCState()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		authURL, err := provider.AuthCodeURL(ctx, state, nonce, challenge)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := noRedirect.Get(authURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		location, err := url.Parse(resp.Header.Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		q := location.Query()
+		if q.Get("state") != state {
+			t.Fatalf("expected state %q, got %q", state, q.Get("state"))
+		}
+		q.Set("nonce", nonce)
+
+		return q
+	}
+
+	t.Run("should exchange a code for verified claims", func(t *testing.T) {
+		verifier, challenge, err := GeneratePKCE()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		q := authorize(t, challenge)
+
+		claims, err := provider.Exchange(ctx, q.Get("code"), verifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if claims.Subject != "test-subject" || claims.Email != "gopher@example.com" || !claims.EmailVerified {
+			t.Errorf("unexpected claims %+v", claims)
+		}
+
+		if claims.Nonce != q.Get("nonce") {
+			t.Errorf("expected nonce %q, got %q", q.Get("nonce"), claims.Nonce)
+		}
+	})
+
+	t.Run("should fail with the wrong code verifier", func(t *testing.T) {
+		_, challenge, err := GeneratePKCE()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		q := authorize(t, challenge)
+
+		if _, err := provider.Exchange(ctx, q.Get("code"), "not-the-verifier"); err == nil {
+			t.Error("expected the exchange to fail")
+		}
+	})
+}
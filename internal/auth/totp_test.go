@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateTOTP(t *testing.T) {
+	// RFC 6238 appendix B SHA1 seed, truncated to 6 digits
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, c := range cases {
+		step, ok := ValidateTOTP(secret, c.code, time.Unix(c.unix, 0))
+		if !ok {
+			t.Errorf("expected code %s to be valid at %d", c.code, c.unix)
+		}
+
+		if step != c.unix/totpPeriod {
+			t.Errorf("expected step %d, got %d", c.unix/totpPeriod, step)
+		}
+	}
+
+	if _, ok := ValidateTOTP(secret, "287082", time.Unix(59+totpPeriod*3, 0)); ok {
+		t.Error("expected code outside of the allowed drift to be rejected")
+	}
+}
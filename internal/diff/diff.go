@@ -0,0 +1,94 @@
+// Package diff computes word-level differences between two texts.
+package diff
+
+import "unicode"
+
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Edit is a run of text kept, inserted or deleted going from the old text to
+// the new one.
+type Edit struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// Words returns the edits turning a into b. Words and the whitespace between
+// them are compared as whole tokens, so joining the Equal and Delete edits
+// gives back a and joining the Equal and Insert edits gives back b.
+func Words(a, b string) []Edit {
+	x, y := tokenize(a), tokenize(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	edits := []Edit{}
+	add := func(op Op, text string) {
+		if n := len(edits); n > 0 && edits[n-1].Op == op {
+			edits[n-1].Text += text
+			return
+		}
+		edits = append(edits, Edit{Op: op, Text: text})
+	}
+
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			add(Equal, x[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			add(Delete, x[i])
+			i++
+		default:
+			add(Insert, y[j])
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		add(Delete, x[i])
+	}
+	for ; j < len(y); j++ {
+		add(Insert, y[j])
+	}
+
+	return edits
+}
+
+// tokenize splits s into alternating runs of whitespace and non-whitespace.
+func tokenize(s string) []string {
+	var tokens []string
+
+	start, space := 0, false
+	for i, r := range s {
+		if i > start && unicode.IsSpace(r) != space {
+			tokens = append(tokens, s[start:i])
+			start = i
+		}
+		space = unicode.IsSpace(r)
+	}
+	if start < len(s) {
+		tokens = append(tokens, s[start:])
+	}
+
+	return tokens
+}
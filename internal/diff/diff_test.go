@@ -0,0 +1,79 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func join(edits []Edit, skip Op) string {
+	var sb strings.Builder
+	for _, e := range edits {
+		if e.Op != skip {
+			sb.WriteString(e.Text)
+		}
+	}
+	return sb.String()
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Edit
+	}{
+		{
+			name: "identical",
+			a:    "hello world",
+			b:    "hello world",
+			want: []Edit{{Equal, "hello world"}},
+		},
+		{
+			name: "replaced word",
+			a:    "the quick fox",
+			b:    "the slow fox",
+			want: []Edit{{Equal, "the "}, {Delete, "quick"}, {Insert, "slow"}, {Equal, " fox"}},
+		},
+		{
+			name: "appended words",
+			a:    "hello",
+			b:    "hello big world",
+			want: []Edit{{Equal, "hello"}, {Insert, " big world"}},
+		},
+		{
+			name: "from empty",
+			a:    "",
+			b:    "hello",
+			want: []Edit{{Insert, "hello"}},
+		},
+		{
+			name: "both empty",
+			a:    "",
+			b:    "",
+			want: []Edit{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Words(tt.a, tt.b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Words(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWordsRebuildsBothTexts(t *testing.T) {
+	a := "Gophers  dig\ttunnels, héllo wörld\n"
+	b := "gophers dig long tunnels\nhéllo wörld"
+
+	edits := Words(a, b)
+
+	if got := join(edits, Insert); got != a {
+		t.Errorf("old text = %q, want %q", got, a)
+	}
+	if got := join(edits, Delete); got != b {
+		t.Errorf("new text = %q, want %q", got, b)
+	}
+}
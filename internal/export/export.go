@@ -0,0 +1,150 @@
+// Package export writes a user's personal data as a ZIP archive of JSON files
+// with an HTML index for people to browse.
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"html/template"
+	"io"
+	"time"
+)
+
+type Profile struct {
+	ID                 int64   `json:"id"`
+	Username           string  `json:"username"`
+	Email              string  `json:"email"`
+	CreatedAt          string  `json:"created_at"`
+	DisplayName        string  `json:"display_name"`
+	Bio                string  `json:"bio"`
+	Website            string  `json:"website"`
+	Location           string  `json:"location"`
+	Birthday           *string `json:"birthday"`
+	BirthdayVisibility string  `json:"birthday_visibility"`
+	IsPrivate          bool    `json:"is_private"`
+}
+
+type Post struct {
+	ID        int64    `json:"id"`
+	Title     string   `json:"title"`
+	Content   string   `json:"content"`
+	Tags      []string `json:"tags"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+}
+
+type Comment struct {
+	ID        int64  `json:"id"`
+	PostID    int64  `json:"post_id"`
+	Content   string `json:"content"`
+	CreatedAt string `json:"created_at"`
+}
+
+// Account is another user the exported user follows or is followed by.
+type Account struct {
+	ID         int64  `json:"id"`
+	Username   string `json:"username"`
+	FollowedAt string `json:"followed_at"`
+}
+
+type Archive struct {
+	Profile   Profile
+	Posts     []Post
+	Comments  []Comment
+	Followers []Account
+	Following []Account
+	CreatedAt time.Time
+}
+
+// Write writes the archive to w as a ZIP file.
+func Write(w io.Writer, a *Archive) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", a.Profile},
+		{"posts.json", a.Posts},
+		{"comments.json", a.Comments},
+		{"followers.json", a.Followers},
+		{"following.json", a.Following},
+	}
+
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: a.CreatedAt})
+		if err != nil {
+			return err
+		}
+
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return err
+		}
+	}
+
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: "index.html", Method: zip.Deflate, Modified: a.CreatedAt})
+	if err != nil {
+		return err
+	}
+
+	if err := index.Execute(fw, a); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+var index = template.Must(template.New("index").Parse(`<!doctype html>
+<html>
+  <head>
+    <meta charset="utf-8" />
+    <title>GopherSocial data export for {{.Profile.Username}}</title>
+  </head>
+  <body>
+    <h1>GopherSocial data export</h1>
+    <p>Created {{.CreatedAt.Format "2006-01-02 15:04 MST"}}. The same data is in the JSON files next to this page.</p>
+
+    <h2>Profile</h2>
+    <dl>
+      <dt>Username</dt><dd>{{.Profile.Username}}</dd>
+      <dt>Email</dt><dd>{{.Profile.Email}}</dd>
+      <dt>Joined</dt><dd>{{.Profile.CreatedAt}}</dd>
+      <dt>Display name</dt><dd>{{.Profile.DisplayName}}</dd>
+      <dt>Bio</dt><dd>{{.Profile.Bio}}</dd>
+      <dt>Website</dt><dd>{{.Profile.Website}}</dd>
+      <dt>Location</dt><dd>{{.Profile.Location}}</dd>
+      <dt>Birthday</dt><dd>{{with .Profile.Birthday}}{{.}}{{end}} ({{.Profile.BirthdayVisibility}})</dd>
+      <dt>Private account</dt><dd>{{if .Profile.IsPrivate}}yes{{else}}no{{end}}</dd>
+    </dl>
+
+    <h2>Posts ({{len .Posts}})</h2>
+    {{range .Posts}}
+    <article>
+      <h3>{{.Title}}</h3>
+      <p><small>{{.CreatedAt}}{{range .Tags}} #{{.}}{{end}}</small></p>
+      <p>{{.Content}}</p>
+    </article>
+    {{end}}
+
+    <h2>Comments ({{len .Comments}})</h2>
+    <ul>
+      {{range .Comments}}<li>{{.CreatedAt}} on post {{.PostID}}: {{.Content}}</li>
+      {{end}}
+    </ul>
+
+    <h2>Followers ({{len .Followers}})</h2>
+    <ul>
+      {{range .Followers}}<li>{{.Username}} since {{.FollowedAt}}</li>
+      {{end}}
+    </ul>
+
+    <h2>Following ({{len .Following}})</h2>
+    <ul>
+      {{range .Following}}<li>{{.Username}} since {{.FollowedAt}}</li>
+      {{end}}
+    </ul>
+  </body>
+</html>
+`))
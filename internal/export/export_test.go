@@ -0,0 +1,69 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	a := &Archive{
+		Profile: Profile{ID: 1, Username: "gopher", Email: "gopher@example.com"},
+		Posts: []Post{
+			{ID: 1, Title: "<script>alert(1)</script>", Content: "hello", Tags: []string{"go"}},
+		},
+		Comments:  []Comment{{ID: 1, PostID: 1, Content: "nice"}},
+		Followers: []Account{{ID: 2, Username: "ferris"}},
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(data)
+	}
+
+	for _, name := range []string{"profile.json", "posts.json", "comments.json", "followers.json", "following.json", "index.html"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("expected %s in the archive", name)
+		}
+	}
+
+	var posts []Post
+	if err := json.Unmarshal([]byte(files["posts.json"]), &posts); err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].Content != "hello" {
+		t.Errorf("unexpected posts %+v", posts)
+	}
+
+	if strings.Contains(files["index.html"], "<script>") {
+		t.Error("expected the index to escape user content")
+	}
+
+	if !strings.Contains(files["index.html"], "ferris") {
+		t.Error("expected the index to list followers")
+	}
+}
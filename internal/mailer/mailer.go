@@ -3,9 +3,14 @@ package mailer
 import "embed"
 
 const (
-	FromName            = "GopherSocial"
-	maxRetries          = 3
-	UserWelcomeTemplate = "user_invitation.tmpl"
+	FromName              = "GopherSocial"
+	maxRetries            = 3
+	UserWelcomeTemplate   = "user_invitation.tmpl"
+	PasswordResetTemplate = "password_reset.tmpl"
+	AccountLockedTemplate = "account_locked.tmpl"
+	EmailChangeTemplate   = "email_change.tmpl"
+	EmailChangedTemplate  = "email_changed.tmpl"
+	DataExportTemplate    = "data_export.tmpl"
 )
 
 //go:embed "templates"
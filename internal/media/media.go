@@ -0,0 +1,143 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+)
+
+var (
+	ErrNotFound        = errors.New("blob not found")
+	ErrUnsupportedType = errors.New("unsupported media type")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+)
+
+// BlobStore keeps uploaded files. Keys are slash separated paths.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// maxPixels guards against decompression bombs when decoding uploads.
+const maxPixels = 40_000_000
+
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Sniff detects the content type from the first bytes of the data, ignoring
+// whatever the client claimed, and returns ErrUnsupportedType for anything
+// that is not an accepted image format.
+func Sniff(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if _, ok := extensions[contentType]; !ok {
+		return "", ErrUnsupportedType
+	}
+
+	return contentType, nil
+}
+
+// Extension returns the file extension for a supported content type.
+func Extension(contentType string) string {
+	return extensions[contentType]
+}
+
+// Dimensions returns the width and height of an encoded image.
+func Dimensions(data []byte) (int, int, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if cfg.Width*cfg.Height > maxPixels {
+		return 0, 0, ErrTooManyPixels
+	}
+
+	return cfg.Width, cfg.Height, nil
+}
+
+// Thumbnail scales the image down to fit in a maxSize square and encodes it
+// as JPEG. Images that already fit are only re-encoded.
+func Thumbnail(data []byte, maxSize int) ([]byte, error) {
+	if _, _, err := Dimensions(data); err != nil {
+		return nil, err
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > maxSize || h > maxSize {
+		if w >= h {
+			h = max(1, h*maxSize/w)
+			w = maxSize
+		} else {
+			w = max(1, w*maxSize/h)
+			h = maxSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	scale(dst, src)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// scale draws src onto dst, averaging the source pixels that fall into each
+// destination pixel and flattening transparency onto white.
+func scale(dst *image.RGBA, src image.Image) {
+	sb := src.Bounds()
+	db := dst.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+	dw, dh := db.Dx(), db.Dy()
+
+	for y := 0; y < dh; y++ {
+		y0 := sb.Min.Y + y*sh/dh
+		y1 := max(y0+1, sb.Min.Y+(y+1)*sh/dh)
+
+		for x := 0; x < dw; x++ {
+			x0 := sb.Min.X + x*sw/dw
+			x1 := max(x0+1, sb.Min.X+(x+1)*sw/dw)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+
+			// the colors are alpha-premultiplied, so white shows through
+			// with the remaining opacity
+			white := 0xffff - a/n
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r/n + white),
+				G: uint16(g/n + white),
+				B: uint16(b/n + white),
+				A: 0xffff,
+			})
+		}
+	}
+}
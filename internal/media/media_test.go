@@ -0,0 +1,119 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"testing"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestBlobStores(t *testing.T) {
+	local, err := NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewTestS3Server()
+	defer srv.Close()
+
+	stores := map[string]BlobStore{
+		"local": local,
+		"s3":    NewS3BlobStore(srv.Config, nil),
+	}
+
+	ctx := context.Background()
+	data := []byte("hello gopher")
+
+	for name, bs := range stores {
+		t.Run(name, func(t *testing.T) {
+			if err := bs.Put(ctx, "1/hello.txt", bytes.NewReader(data), int64(len(data)), "text/plain"); err != nil {
+				t.Fatal(err)
+			}
+
+			rc, err := bs.Get(ctx, "1/hello.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, data) {
+				t.Errorf("expected %q, got %q", data, got)
+			}
+
+			if err := bs.Delete(ctx, "1/hello.txt"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := bs.Get(ctx, "1/hello.txt"); err != ErrNotFound {
+				t.Errorf("expected ErrNotFound after delete, got %v", err)
+			}
+		})
+	}
+
+	t.Run("local should reject keys outside the root", func(t *testing.T) {
+		if err := local.Put(ctx, "../escape", bytes.NewReader(data), int64(len(data)), "text/plain"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("s3 should reject bad credentials", func(t *testing.T) {
+		cfg := srv.Config
+		cfg.SecretKey = "wrong"
+
+		if err := NewS3BlobStore(cfg, nil).Put(ctx, "x", bytes.NewReader(data), int64(len(data)), "text/plain"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestSniff(t *testing.T) {
+	contentType, err := Sniff(testPNG(t, 2, 2))
+	if err != nil || contentType != "image/png" {
+		t.Errorf("expected image/png, got %q (%v)", contentType, err)
+	}
+
+	if _, err := Sniff([]byte("<html><script>alert(1)</script></html>")); err != ErrUnsupportedType {
+		t.Errorf("expected ErrUnsupportedType, got %v", err)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	thumb, err := Thumbnail(testPNG(t, 400, 200), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, h, err := Dimensions(thumb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w != 100 || h != 50 {
+		t.Errorf("expected a 100x50 thumbnail, got %dx%d", w, h)
+	}
+}
@@ -0,0 +1,191 @@
+package media
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type S3Config struct {
+	// Endpoint is the base URL of the service, e.g. https://s3.eu-west-1.amazonaws.com
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// S3BlobStore keeps blobs in a bucket of an S3 compatible service. Requests
+// use path-style addressing and are signed with AWS Signature Version 4.
+type S3BlobStore struct {
+	cfg    S3Config
+	client *http.Client
+}
+
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3UnsignedBody    = "UNSIGNED-PAYLOAD"
+	s3AmzDateFormat   = "20060102T150405Z"
+	s3ScopeDateFormat = "20060102"
+)
+
+func NewS3BlobStore(cfg S3Config, client *http.Client) *S3BlobStore {
+	if client == nil {
+		client = &http.Client{Timeout: time.Minute}
+	}
+
+	cfg.Endpoint = strings.TrimSuffix(cfg.Endpoint, "/")
+
+	return &S3BlobStore{cfg: cfg, client: client}
+}
+
+func (s *S3BlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+func (s *S3BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+func (s *S3BlobStore) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+
+	u := fmt.Sprintf("%s/%s/%s", s.cfg.Endpoint, url.PathEscape(s.cfg.Bucket), strings.Join(segments, "/"))
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+
+	signS3Request(req, s.cfg, time.Now().UTC())
+
+	return req, nil
+}
+
+func (s *S3BlobStore) do(req *http.Request) (*http.Response, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	case resp.StatusCode >= 300:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("s3 %s %s returned %s: %s", req.Method, req.URL.Path, resp.Status, msg)
+	}
+
+	return resp, nil
+}
+
+// signS3Request adds an AWS Signature Version 4 Authorization header. The
+// payload is not included in the signature so uploads can be streamed.
+func signS3Request(req *http.Request, cfg S3Config, now time.Time) {
+	amzDate := now.Format(s3AmzDateFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedBody)
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", now.Format(s3ScopeDateFormat), cfg.Region)
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+
+	signature := s3Signature(req, cfg, amzDate, scope, signedHeaders)
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, cfg.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+func s3Signature(req *http.Request, cfg S3Config, amzDate, scope, signedHeaders string) string {
+	var headers strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.Host
+			if value == "" {
+				value = req.URL.Host
+			}
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		headers.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{s3Algorithm, amzDate, scope, hex.EncodeToString(hash[:])}, "\n")
+
+	date, _, _ := strings.Cut(scope, "/")
+	key := hmacSHA256([]byte("AWS4"+cfg.SecretKey), date)
+	key = hmacSHA256(key, cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
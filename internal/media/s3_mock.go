@@ -0,0 +1,103 @@
+package media
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TestS3Server is an in-memory stand-in for an S3 compatible service. It
+// checks request signatures against its credentials.
+type TestS3Server struct {
+	*httptest.Server
+	Config S3Config
+
+	mu      sync.Mutex
+	objects map[string]testS3Object
+}
+
+type testS3Object struct {
+	data        []byte
+	contentType string
+}
+
+func NewTestS3Server() *TestS3Server {
+	s := &TestS3Server{
+		objects: make(map[string]testS3Object),
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.Config = S3Config{
+		Endpoint:  s.URL,
+		Region:    "us-east-1",
+		Bucket:    "test-bucket",
+		AccessKey: "test-access-key",
+		SecretKey: "test-secret-key",
+	}
+
+	return s
+}
+
+// Len returns the number of stored objects.
+func (s *TestS3Server) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.objects)
+}
+
+func (s *TestS3Server) serve(w http.ResponseWriter, r *http.Request) {
+	if !s.validSignature(r) {
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+
+	prefix := "/" + s.Config.Bucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.objects[key] = testS3Object{data: data, contentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		obj, ok := s.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		_, _ = w.Write(obj.data)
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *TestS3Server) validSignature(r *http.Request) bool {
+	amzDate := r.Header.Get("X-Amz-Date")
+	signed, err := time.Parse(s3AmzDateFormat, amzDate)
+	if err != nil || time.Since(signed) > time.Minute*15 {
+		return false
+	}
+
+	want := r.Clone(r.Context())
+	signS3Request(want, s.Config, signed)
+
+	return r.Header.Get("Authorization") == want.Header.Get("Authorization")
+}
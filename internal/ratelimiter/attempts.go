@@ -0,0 +1,136 @@
+package ratelimiter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrContended is returned when an update of login attempts keeps losing to
+// concurrent ones.
+var ErrContended = errors.New("too many concurrent login attempts")
+
+// sweepInterval is how often MemoryAttemptStore drops expired entries.
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	attempts Attempts
+	expires  time.Time
+}
+
+type MemoryAttemptStore struct {
+	sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{
+		entries:   make(map[string]memoryEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryAttemptStore) Update(ctx context.Context, key string, ttl time.Duration, fn func(Attempts) Attempts) error {
+	s.Lock()
+	defer s.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	var attempts Attempts
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expires) {
+		attempts = entry.attempts
+	}
+
+	s.entries[key] = memoryEntry{attempts: fn(attempts), expires: now.Add(ttl)}
+
+	return nil
+}
+
+func (s *MemoryAttemptStore) Delete(ctx context.Context, key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.entries, key)
+
+	return nil
+}
+
+// sweep drops the expired entries, so keys that are never tried again, like
+// the ones of a password spray, don't pile up.
+func (s *MemoryAttemptStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+
+	for key, entry := range s.entries {
+		if now.After(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+
+	s.lastSweep = now
+}
+
+// maxUpdateRetries bounds how often RedisAttemptStore retries an update that
+// raced with another one.
+const maxUpdateRetries = 10
+
+// RedisAttemptStore shares login attempts between every API instance.
+type RedisAttemptStore struct {
+	rdb *redis.Client
+}
+
+func NewRedisAttemptStore(rdb *redis.Client) *RedisAttemptStore {
+	return &RedisAttemptStore{rdb: rdb}
+}
+
+// Update reads and writes the attempts in a transaction that fails if the key
+// changed in between, in which case it starts over.
+func (s *RedisAttemptStore) Update(ctx context.Context, key string, ttl time.Duration, fn func(Attempts) Attempts) error {
+	key = "login-" + key
+
+	update := func(tx *redis.Tx) error {
+		var attempts Attempts
+
+		data, err := tx.Get(ctx, key).Result()
+		switch {
+		case err == redis.Nil:
+		case err != nil:
+			return err
+		default:
+			if err := json.Unmarshal([]byte(data), &attempts); err != nil {
+				return err
+			}
+		}
+
+		updated, err := json.Marshal(fn(attempts))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, updated, ttl)
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < maxUpdateRetries; i++ {
+		err := s.rdb.Watch(ctx, update, key)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+
+	return ErrContended
+}
+
+func (s *RedisAttemptStore) Delete(ctx context.Context, key string) error {
+	return s.rdb.Del(ctx, "login-"+key).Err()
+}
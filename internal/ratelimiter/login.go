@@ -0,0 +1,189 @@
+package ratelimiter
+
+import (
+	"context"
+	"time"
+)
+
+// Attempts is the failed login state kept for one account or IP.
+type Attempts struct {
+	// Failures counts the attempts that failed or are still being checked
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// AttemptStore persists Attempts, forgetting them after ttl.
+type AttemptStore interface {
+	// Update atomically replaces the attempts of key with the result of fn.
+	// fn may be called more than once if another update interferes.
+	Update(ctx context.Context, key string, ttl time.Duration, fn func(Attempts) Attempts) error
+	Delete(ctx context.Context, key string) error
+}
+
+type LoginConfig struct {
+	// BaseDelay is the wait after the first failure; it doubles with every
+	// further failure up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// AccountMaxAttempts and IPMaxAttempts are the failures after which the
+	// account or IP gets locked for LockoutDuration.
+	AccountMaxAttempts int
+	IPMaxAttempts      int
+	LockoutDuration    time.Duration
+	// Window is how long failures are remembered after the last one.
+	Window time.Duration
+}
+
+// Limit is an account or IP whose login attempts are limited, locked after
+// MaxAttempts failures.
+type Limit struct {
+	Key         string
+	MaxAttempts int
+}
+
+// Verdict is the outcome of LoginGuard.Attempt.
+type Verdict struct {
+	// Wait is how long the caller has to wait before trying again, zero when
+	// the attempt may go ahead
+	Wait time.Duration
+	// Locked is the key whose lockout is the reason to wait, empty when the
+	// wait is a backoff
+	Locked string
+	// Locking lists the keys that reached their maximum attempts with this
+	// attempt; they stay locked unless it succeeds
+	Locking []string
+}
+
+// LoginGuard applies exponential backoff and temporary lockouts to failed
+// logins.
+type LoginGuard struct {
+	store AttemptStore
+	cfg   LoginConfig
+}
+
+func NewLoginGuard(store AttemptStore, cfg LoginConfig) *LoginGuard {
+	return &LoginGuard{
+		store: store,
+		cfg:   cfg,
+	}
+}
+
+// Attempt counts a login attempt against every limit before the credentials
+// are checked, so concurrent attempts cannot get past a limit. The caller
+// then reports a success with Reset or Forgive; a failure needs no further
+// call. When the attempt has to wait, nothing is counted.
+func (g *LoginGuard) Attempt(ctx context.Context, limits ...Limit) (Verdict, error) {
+	var v Verdict
+
+	for i, l := range limits {
+		var (
+			wait    time.Duration
+			locked  bool
+			locking bool
+		)
+
+		err := g.store.Update(ctx, l.Key, g.ttl(), func(a Attempts) Attempts {
+			now := time.Now()
+			wait, locked, locking = 0, false, false
+
+			// a lockout that ran out starts the count afresh
+			if !a.LockedUntil.IsZero() && !a.LockedUntil.After(now) {
+				a = Attempts{}
+			}
+
+			switch {
+			case a.LockedUntil.After(now):
+				wait, locked = a.LockedUntil.Sub(now), true
+			case a.Failures > 0 && a.LastFailure.Add(g.delay(a.Failures)).After(now):
+				wait = a.LastFailure.Add(g.delay(a.Failures)).Sub(now)
+			default:
+				a.Failures++
+				a.LastFailure = now
+
+				if a.Failures >= l.MaxAttempts {
+					a.LockedUntil = now.Add(g.cfg.LockoutDuration)
+					locking = true
+				}
+			}
+
+			return a
+		})
+		if err != nil {
+			g.forgive(ctx, limits[:i])
+			return Verdict{}, err
+		}
+
+		if wait > 0 {
+			g.forgive(ctx, limits[:i])
+
+			refused := Verdict{Wait: wait}
+			if locked {
+				refused.Locked = l.Key
+			}
+
+			return refused, nil
+		}
+
+		if locking {
+			v.Locking = append(v.Locking, l.Key)
+		}
+	}
+
+	return v, nil
+}
+
+// Forgive takes back an attempt counted for l that turned out not to be a
+// failure, lifting the lockout it caused.
+func (g *LoginGuard) Forgive(ctx context.Context, l Limit) error {
+	return g.store.Update(ctx, l.Key, g.ttl(), func(a Attempts) Attempts {
+		if a.Failures > 0 {
+			a.Failures--
+		}
+
+		if a.Failures < l.MaxAttempts {
+			a.LockedUntil = time.Time{}
+		}
+
+		return a
+	})
+}
+
+// Reset forgets every failure for key, which also lifts a lockout.
+func (g *LoginGuard) Reset(ctx context.Context, key string) error {
+	return g.store.Delete(ctx, key)
+}
+
+func (g *LoginGuard) Config() LoginConfig {
+	return g.cfg
+}
+
+// forgive takes back the attempts counted for limits when a later limit
+// refuses the attempt.
+func (g *LoginGuard) forgive(ctx context.Context, limits []Limit) {
+	for _, l := range limits {
+		// the attempt stays counted if this fails, which errs on the safe side
+		_ = g.Forgive(ctx, l)
+	}
+}
+
+func (g *LoginGuard) ttl() time.Duration {
+	if g.cfg.LockoutDuration > g.cfg.Window {
+		return g.cfg.LockoutDuration
+	}
+
+	return g.cfg.Window
+}
+
+func (g *LoginGuard) delay(failures int) time.Duration {
+	d := g.cfg.BaseDelay
+	for i := 1; i < failures && d < g.cfg.MaxDelay; i++ {
+		d *= 2
+	}
+
+	if d > g.cfg.MaxDelay {
+		return g.cfg.MaxDelay
+	}
+
+	return d
+}
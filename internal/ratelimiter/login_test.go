@@ -0,0 +1,190 @@
+package ratelimiter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoginGuard(t *testing.T) {
+	ctx := context.Background()
+	cfg := LoginConfig{
+		BaseDelay:          time.Second,
+		MaxDelay:           time.Second * 4,
+		AccountMaxAttempts: 3,
+		IPMaxAttempts:      10,
+		LockoutDuration:    time.Minute,
+		Window:             time.Hour,
+	}
+
+	// without backoff the attempts only run into the lockouts
+	noBackoff := cfg
+	noBackoff.BaseDelay, noBackoff.MaxDelay = 0, 0
+
+	account := Limit{Key: "account", MaxAttempts: cfg.AccountMaxAttempts}
+	ip := Limit{Key: "ip", MaxAttempts: cfg.IPMaxAttempts}
+
+	t.Run("should back off exponentially up to the max delay", func(t *testing.T) {
+		g := NewLoginGuard(NewMemoryAttemptStore(), cfg)
+
+		expected := []time.Duration{0, time.Second, time.Second * 2, time.Second * 4, time.Second * 4}
+		for i, want := range expected {
+			if got := g.delay(i); i > 0 && got != want {
+				t.Errorf("failures %d: expected delay %v, got %v", i, want, got)
+			}
+		}
+	})
+
+	t.Run("should make the next attempt wait", func(t *testing.T) {
+		g := NewLoginGuard(NewMemoryAttemptStore(), cfg)
+
+		if v, err := g.Attempt(ctx, account, ip); err != nil || v.Wait != 0 {
+			t.Fatalf("expected the first attempt to go ahead, got %+v, %v", v, err)
+		}
+
+		v, err := g.Attempt(ctx, account, ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v.Wait <= 0 || v.Locked != "" {
+			t.Errorf("expected a backoff, got %+v", v)
+		}
+	})
+
+	t.Run("should lock the key after too many attempts", func(t *testing.T) {
+		g := NewLoginGuard(NewMemoryAttemptStore(), noBackoff)
+
+		for i := 1; i <= cfg.AccountMaxAttempts; i++ {
+			v, err := g.Attempt(ctx, ip, account)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			locking := len(v.Locking) == 1 && v.Locking[0] == account.Key
+			if v.Wait != 0 || locking != (i == cfg.AccountMaxAttempts) {
+				t.Errorf("attempt %d: unexpected verdict %+v", i, v)
+			}
+		}
+
+		v, err := g.Attempt(ctx, ip, account)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v.Locked != account.Key || v.Wait <= time.Second*4 {
+			t.Errorf("expected the account to be locked, got %+v", v)
+		}
+
+		if err := g.Reset(ctx, account.Key); err != nil {
+			t.Fatal(err)
+		}
+
+		if v, _ := g.Attempt(ctx, ip, account); v.Wait != 0 {
+			t.Errorf("expected reset to lift the lockout, got %+v", v)
+		}
+	})
+
+	t.Run("should not count attempts refused by another key", func(t *testing.T) {
+		g := NewLoginGuard(NewMemoryAttemptStore(), noBackoff)
+		lockedIP := Limit{Key: "ip", MaxAttempts: 1}
+
+		if _, err := g.Attempt(ctx, lockedIP); err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < cfg.AccountMaxAttempts*2; i++ {
+			v, err := g.Attempt(ctx, account, lockedIP)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if v.Locked != lockedIP.Key {
+				t.Fatalf("expected the IP to be locked, got %+v", v)
+			}
+		}
+
+		if v, _ := g.Attempt(ctx, account); v.Wait != 0 {
+			t.Errorf("expected the account to be unaffected, got %+v", v)
+		}
+	})
+
+	t.Run("should lift a lockout when the locking attempt is forgiven", func(t *testing.T) {
+		g := NewLoginGuard(NewMemoryAttemptStore(), noBackoff)
+		single := Limit{Key: "ip", MaxAttempts: 1}
+
+		if _, err := g.Attempt(ctx, single); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := g.Forgive(ctx, single); err != nil {
+			t.Fatal(err)
+		}
+
+		if v, _ := g.Attempt(ctx, single); v.Wait != 0 {
+			t.Errorf("expected the lockout to be lifted, got %+v", v)
+		}
+	})
+
+	t.Run("should not let concurrent attempts past the limit", func(t *testing.T) {
+		g := NewLoginGuard(NewMemoryAttemptStore(), noBackoff)
+
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			allowed int
+		)
+
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				v, err := g.Attempt(ctx, account)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				if v.Wait == 0 {
+					mu.Lock()
+					allowed++
+					mu.Unlock()
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		if allowed != cfg.AccountMaxAttempts {
+			t.Errorf("expected %d attempts to go ahead, got %d", cfg.AccountMaxAttempts, allowed)
+		}
+	})
+}
+
+func TestMemoryAttemptStoreSweep(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryAttemptStore()
+
+	keep := func(a Attempts) Attempts { return a }
+
+	if err := s.Update(ctx, "expired", time.Millisecond, keep); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	s.lastSweep = time.Now().Add(-sweepInterval)
+
+	if err := s.Update(ctx, "fresh", time.Hour, keep); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.entries["expired"]; ok {
+		t.Error("expected the expired entry to be swept")
+	}
+
+	if _, ok := s.entries["fresh"]; !ok {
+		t.Error("expected the fresh entry to be kept")
+	}
+}
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type APIKey struct {
+	ID         int64    `json:"id"`
+	UserID     int64    `json:"user_id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  *string  `json:"expires_at"`
+	LastUsedAt *string  `json:"last_used_at"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type APIKeyStore struct {
+	db *sql.DB
+}
+
+// Create stores the key under the hash of token. A nil expiry means the key
+// never expires.
+func (s *APIKeyStore) Create(ctx context.Context, key *APIKey, token string, expiry *time.Time) error {
+	query := `
+	INSERT INTO api_keys (user_id, name, token, prefix, scopes, expiry)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, expiry, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return s.db.QueryRowContext(
+		ctx,
+		query,
+		key.UserID,
+		key.Name,
+		hashToken(token),
+		key.Prefix,
+		pq.Array(key.Scopes),
+		expiry,
+	).Scan(
+		&key.ID,
+		&key.ExpiresAt,
+		&key.CreatedAt,
+	)
+}
+
+func (s *APIKeyStore) GetByUserID(ctx context.Context, userID int64) ([]APIKey, error) {
+	query := `
+	SELECT id, user_id, name, prefix, scopes, expiry, last_used_at, created_at
+	FROM api_keys
+	WHERE user_id = $1
+	ORDER BY created_at DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		var k APIKey
+		if err := rows.Scan(
+			&k.ID,
+			&k.UserID,
+			&k.Name,
+			&k.Prefix,
+			pq.Array(&k.Scopes),
+			&k.ExpiresAt,
+			&k.LastUsedAt,
+			&k.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	return keys, rows.Err()
+}
+
+// GetByToken returns the unexpired key matching token.
+func (s *APIKeyStore) GetByToken(ctx context.Context, token string) (*APIKey, error) {
+	query := `
+	SELECT id, user_id, name, prefix, scopes, expiry, last_used_at, created_at
+	FROM api_keys
+	WHERE token = $1 AND (expiry IS NULL OR expiry > $2)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	k := &APIKey{}
+	err := s.db.QueryRowContext(ctx, query, hashToken(token), time.Now()).Scan(
+		&k.ID,
+		&k.UserID,
+		&k.Name,
+		&k.Prefix,
+		pq.Array(&k.Scopes),
+		&k.ExpiresAt,
+		&k.LastUsedAt,
+		&k.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return k, nil
+}
+
+// Touch records that the key was just used.
+func (s *APIKeyStore) Touch(ctx context.Context, key *APIKey) error {
+	query := `UPDATE api_keys SET last_used_at = NOW() WHERE id = $1 RETURNING last_used_at`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, key.ID).Scan(&key.LastUsedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *APIKeyStore) Delete(ctx context.Context, id, userID int64) error {
+	query := `DELETE FROM api_keys WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
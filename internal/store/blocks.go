@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+var ErrBlocked = errors.New("user is blocked")
+
+type BlockStore struct {
+	db *sql.DB
+}
+
+// Block stores the block and removes follows and follow requests between
+// the two users in both directions.
+func (s *BlockStore) Block(ctx context.Context, blockerID, userID int64) error {
+	query := `
+	INSERT INTO blocks (blocker_id, blocked_id)
+	VALUES ($1, $2)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, query, blockerID, userID); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok {
+				switch pqErr.Code {
+				case "23505":
+					return ErrConflict
+				case "23503":
+					return ErrNotFound
+				}
+			}
+
+			return err
+		}
+
+		if err := unfollow(ctx, tx, blockerID, userID); err != nil {
+			return err
+		}
+
+		return unfollow(ctx, tx, userID, blockerID)
+	})
+}
+
+// Unblock lifts the block. Follows removed by the block are not restored.
+func (s *BlockStore) Unblock(ctx context.Context, blockerID, userID int64) error {
+	query := `
+	DELETE FROM blocks WHERE blocker_id = $1 AND blocked_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, blockerID, userID)
+	return err
+}
@@ -9,7 +9,9 @@ import (
 
 func NewMockCache() Storage {
 	return Storage{
-		Users: &MockUserStore{},
+		Users:       &MockUserStore{},
+		Sessions:    &MockSessionStore{},
+		Suggestions: &MockSuggestionStore{},
 	}
 }
 
@@ -30,3 +32,48 @@ func (m *MockUserStore) Set(ctx context.Context, user *store.User) error {
 func (m *MockUserStore) Delete(ctx context.Context, userID int64) {
 	m.Called(userID)
 }
+
+type MockSessionStore struct {
+	mock.Mock
+}
+
+func (m *MockSessionStore) Get(ctx context.Context, id string) (*store.Session, error) {
+	args := m.Called(id)
+	session, _ := args.Get(0).(*store.Session)
+	return session, args.Error(1)
+}
+
+func (m *MockSessionStore) Set(ctx context.Context, session *store.Session) error {
+	args := m.Called(session)
+	return args.Error(0)
+}
+
+func (m *MockSessionStore) Delete(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockSessionStore) DeleteForUser(ctx context.Context, userID int64) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+type MockSuggestionStore struct {
+	mock.Mock
+}
+
+func (m *MockSuggestionStore) Get(ctx context.Context, userID int64) ([]store.Suggestion, error) {
+	args := m.Called(userID)
+	suggestions, _ := args.Get(0).([]store.Suggestion)
+	return suggestions, args.Error(1)
+}
+
+func (m *MockSuggestionStore) Set(ctx context.Context, userID int64, suggestions []store.Suggestion) error {
+	args := m.Called(userID, suggestions)
+	return args.Error(0)
+}
+
+func (m *MockSuggestionStore) Delete(ctx context.Context, userID int64) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/go-redis/redis/v8"
+)
+
+// SessionStore caches active sessions so access tokens can be checked without
+// a database round trip. Revoking a session drops its entry, and revoking all
+// of a user's sessions drops them with DeleteForUser, so a revoked session is
+// never served from the cache.
+type SessionStore struct {
+	rdb *redis.Client
+}
+
+const SessionExpTime = time.Minute
+
+func (s *SessionStore) Get(ctx context.Context, id string) (*store.Session, error) {
+	cacheKey := fmt.Sprintf("session-%v", id)
+
+	data, err := s.rdb.Get(ctx, cacheKey).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var session store.Session
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// Set caches the session and adds it to the index of its user's cached
+// sessions, which outlives every session in it.
+func (s *SessionStore) Set(ctx context.Context, session *store.Session) error {
+	cacheKey := fmt.Sprintf("session-%v", session.ID)
+	userKey := fmt.Sprintf("user-sessions-%v", session.UserID)
+
+	json, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, cacheKey, json, SessionExpTime)
+		pipe.SAdd(ctx, userKey, session.ID)
+		pipe.Expire(ctx, userKey, SessionExpTime)
+		return nil
+	})
+	return err
+}
+
+func (s *SessionStore) Delete(ctx context.Context, id string) error {
+	cacheKey := fmt.Sprintf("session-%v", id)
+
+	return s.rdb.Del(ctx, cacheKey).Err()
+}
+
+// DeleteForUser drops every cached session of the user.
+func (s *SessionStore) DeleteForUser(ctx context.Context, userID int64) error {
+	userKey := fmt.Sprintf("user-sessions-%v", userID)
+
+	ids, err := s.rdb.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := []string{userKey}
+	for _, id := range ids {
+		keys = append(keys, fmt.Sprintf("session-%v", id))
+	}
+
+	return s.rdb.Del(ctx, keys...).Err()
+}
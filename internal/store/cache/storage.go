@@ -11,11 +11,25 @@ type Storage struct {
 	Users interface {
 		Get(ctx context.Context, id int64) (*store.User, error)
 		Set(ctx context.Context, user *store.User) error
+		Delete(ctx context.Context, userID int64)
+	}
+	Sessions interface {
+		Get(ctx context.Context, id string) (*store.Session, error)
+		Set(ctx context.Context, session *store.Session) error
+		Delete(ctx context.Context, id string) error
+		DeleteForUser(ctx context.Context, userID int64) error
+	}
+	Suggestions interface {
+		Get(ctx context.Context, userID int64) ([]store.Suggestion, error)
+		Set(ctx context.Context, userID int64, suggestions []store.Suggestion) error
+		Delete(ctx context.Context, userID int64) error
 	}
 }
 
 func NewRedisStorage(rdb *redis.Client) Storage {
 	return Storage{
-		Users: &UserStore{rdb: rdb},
+		Users:       &UserStore{rdb: rdb},
+		Sessions:    &SessionStore{rdb: rdb},
+		Suggestions: &SuggestionStore{rdb: rdb},
 	}
 }
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ana-tonic/gopher-social/internal/store"
+	"github.com/go-redis/redis/v8"
+)
+
+// SuggestionStore caches the follow suggestions of each user. Following,
+// blocking or muting someone drops the entry, other changes to the graph
+// show up after SuggestionExpTime.
+type SuggestionStore struct {
+	rdb *redis.Client
+}
+
+const SuggestionExpTime = 15 * time.Minute
+
+func (s *SuggestionStore) Get(ctx context.Context, userID int64) ([]store.Suggestion, error) {
+	cacheKey := fmt.Sprintf("suggestions-%v", userID)
+
+	data, err := s.rdb.Get(ctx, cacheKey).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var suggestions []store.Suggestion
+	if err := json.Unmarshal([]byte(data), &suggestions); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+func (s *SuggestionStore) Set(ctx context.Context, userID int64, suggestions []store.Suggestion) error {
+	cacheKey := fmt.Sprintf("suggestions-%v", userID)
+
+	json, err := json.Marshal(suggestions)
+	if err != nil {
+		return err
+	}
+
+	return s.rdb.Set(ctx, cacheKey, json, SuggestionExpTime).Err()
+}
+
+func (s *SuggestionStore) Delete(ctx context.Context, userID int64) error {
+	cacheKey := fmt.Sprintf("suggestions-%v", userID)
+
+	return s.rdb.Del(ctx, cacheKey).Err()
+}
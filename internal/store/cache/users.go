@@ -16,6 +16,13 @@ type UserStore struct {
 
 const UserExpTime = time.Minute
 
+// cachedUser keeps the fields the auth middleware needs but that are left
+// out of the user's JSON.
+type cachedUser struct {
+	*store.User
+	TOTPEnabled bool `json:"totp_enabled"`
+}
+
 func (s *UserStore) Get(ctx context.Context, userID int64) (*store.User, error) {
 	cacheKey := fmt.Sprintf("user-%v", userID)
 
@@ -28,10 +35,12 @@ func (s *UserStore) Get(ctx context.Context, userID int64) (*store.User, error)
 
 	var user store.User
 	if data != "" {
-		err = json.Unmarshal([]byte(data), &user)
+		cached := cachedUser{User: &user}
+		err = json.Unmarshal([]byte(data), &cached)
 		if err != nil {
 			return nil, err
 		}
+		user.TOTPEnabled = cached.TOTPEnabled
 	}
 
 	return &user, nil
@@ -40,10 +49,15 @@ func (s *UserStore) Get(ctx context.Context, userID int64) (*store.User, error)
 func (s *UserStore) Set(ctx context.Context, user *store.User) error {
 	cacheKey := fmt.Sprintf("user-%v", user.ID)
 
-	json, err := json.Marshal(user)
+	json, err := json.Marshal(cachedUser{User: user, TOTPEnabled: user.TOTPEnabled})
 	if err != nil {
 		return err
 	}
 
 	return s.rdb.Set(ctx, cacheKey, json, UserExpTime).Err()
 }
+
+func (s *UserStore) Delete(ctx context.Context, userID int64) {
+	cacheKey := fmt.Sprintf("user-%v", userID)
+	s.rdb.Del(ctx, cacheKey)
+}
@@ -3,34 +3,137 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 )
 
 type Comment struct {
-	ID        int64  `json:"id"`
-	PostID    int64  `json:"post_id"`
-	UserID    int64  `json:"user_id"`
-	Content   string `json:"content"`
-	CreatedAt string `json:"created_at"`
-	User      User   `json:"user"`
+	ID     int64 `json:"id"`
+	PostID int64 `json:"post_id"`
+	UserID int64 `json:"user_id"`
+	// ParentID is the comment this one replies to, nil for top-level comments
+	ParentID *int64 `json:"parent_id"`
+	// Depth is 0 for top-level comments and one more than the parent's for
+	// replies
+	Depth          int            `json:"depth"`
+	ReplyCount     int            `json:"reply_count"`
+	Content        string         `json:"content"`
+	CreatedAt      string         `json:"created_at"`
+	EditedAt       *string        `json:"edited_at"`
+	User           User           `json:"user"`
+	ReactionCounts ReactionCounts `json:"reaction_counts"`
+	// MyReaction is the emoji the authenticated user reacted with, if any
+	MyReaction *string `json:"my_reaction"`
 }
 
 type CommentStore struct {
 	db *sql.DB
 }
 
-func (s *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]Comment, error) {
-	query := `SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, users.username, users.id
+func (s *CommentStore) GetByID(ctx context.Context, id int64) (*Comment, error) {
+	query := `
+	SELECT c.id, c.post_id, c.user_id, c.parent_id, c.depth, c.content, c.created_at, c.edited_at, c.reaction_counts,
+		users.username, users.id
 	FROM comments c
 	JOIN users ON users.id = c.user_id
-	WHERE c.post_id = $1
-	ORDER BY c.created_at DESC;`
+	WHERE c.id = $1
+	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query, postID)
+	var c Comment
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&c.ID,
+		&c.PostID,
+		&c.UserID,
+		&c.ParentID,
+		&c.Depth,
+		&c.Content,
+		&c.CreatedAt,
+		&c.EditedAt,
+		&c.ReactionCounts,
+		&c.User.Username,
+		&c.User.ID,
+	)
 	if err != nil {
-		return nil, err
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &c, nil
+}
+
+// GetByPostID returns the top-level comments of a post, most recent first,
+// and the cursor of the next page, which is empty on the last page. It
+// leaves out comments from users the viewer has muted and from users on
+// either side of a block with the viewer.
+func (s *CommentStore) GetByPostID(ctx context.Context, postID, viewerID int64, cq CursorQuery) ([]Comment, string, error) {
+	query := commentSelect + `
+	WHERE c.post_id = $1 AND c.parent_id IS NULL
+		AND ` + commentVisible("c") + `
+		AND ($3::timestamptz IS NULL OR (c.created_at, c.id) < ($3, $4))
+	ORDER BY c.created_at DESC, c.id DESC
+	LIMIT $5
+	`
+
+	return s.list(ctx, query, postID, viewerID, cq)
+}
+
+// GetReplies returns the direct replies to a comment, oldest first. It pages
+// and filters the same way as GetByPostID.
+func (s *CommentStore) GetReplies(ctx context.Context, commentID, viewerID int64, cq CursorQuery) ([]Comment, string, error) {
+	query := commentSelect + `
+	WHERE c.parent_id = $1
+		AND ` + commentVisible("c") + `
+		AND ($3::timestamptz IS NULL OR (c.created_at, c.id) > ($3, $4))
+	ORDER BY c.created_at, c.id
+	LIMIT $5
+	`
+
+	return s.list(ctx, query, commentID, viewerID, cq)
+}
+
+// commentSelect selects the comments scanned by list, with $2 the viewer. The
+// replies are counted as the viewer sees them.
+var commentSelect = `
+	SELECT c.id, c.post_id, c.user_id, c.parent_id, c.depth,
+		(SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id AND ` + commentVisible("r") + `),
+		c.content, c.created_at, c.edited_at, users.username, users.id,
+		c.reaction_counts, cr.emoji
+	FROM comments c
+	JOIN users ON users.id = c.user_id
+	LEFT JOIN comment_reactions cr ON cr.comment_id = c.id AND cr.user_id = $2`
+
+// commentVisible leaves out the comments, named by alias, of deactivated
+// users and of users muted by or on either side of a block with $2.
+func commentVisible(alias string) string {
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM users cu WHERE cu.id = %[1]s.user_id AND `+authorShown("cu")+`)
+		AND NOT EXISTS (
+			SELECT 1 FROM blocks b
+			WHERE (b.blocker_id = %[1]s.user_id AND b.blocked_id = $2) OR (b.blocker_id = $2 AND b.blocked_id = %[1]s.user_id)
+		)
+		AND NOT EXISTS (SELECT 1 FROM mutes m WHERE m.muter_id = $2 AND m.muted_id = %[1]s.user_id)`, alias)
+}
+
+func (s *CommentStore) list(ctx context.Context, query string, id, viewerID int64, cq CursorQuery) ([]Comment, string, error) {
+	var afterTime *string
+	var afterID int64
+	if cq.After != nil {
+		afterTime, afterID = &cq.After.CreatedAt, cq.After.ID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	// one extra row tells whether there is a next page
+	rows, err := s.db.QueryContext(ctx, query, id, viewerID, afterTime, afterID, cq.Limit+1)
+	if err != nil {
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -42,25 +145,57 @@ func (s *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]Comment
 			&c.ID,
 			&c.PostID,
 			&c.UserID,
+			&c.ParentID,
+			&c.Depth,
+			&c.ReplyCount,
 			&c.Content,
 			&c.CreatedAt,
+			&c.EditedAt,
 			&c.User.Username,
 			&c.User.ID,
+			&c.ReactionCounts,
+			&c.MyReaction,
 		)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		comments = append(comments, c)
 	}
 
-	return comments, nil
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(comments) > cq.Limit {
+		comments = comments[:cq.Limit]
+		last := comments[len(comments)-1]
+		next = Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	return comments, next, nil
 }
 
+// Create stores the comment, as a reply when comment.ParentID is set. The
+// caller checks that the parent is on the same post. It returns ErrBlocked
+// if the author of the post, or of the parent comment, has blocked the
+// commenter.
 func (s *CommentStore) Create(ctx context.Context, comment *Comment) error {
 	query := `
-	INSERT INTO comments (post_id, user_id, content) 
-	VALUES ($1, $2, $3) 
-	RETURNING id, created_at
+	INSERT INTO comments (post_id, user_id, content, parent_id, depth)
+	SELECT $1::bigint, $2::bigint, $3, $4::bigint,
+		COALESCE((SELECT depth + 1 FROM comments WHERE id = $4), 0)
+	WHERE NOT EXISTS (
+		SELECT 1 FROM posts p
+		JOIN blocks b ON b.blocker_id = p.user_id
+		WHERE p.id = $1 AND b.blocked_id = $2
+	)
+	AND NOT EXISTS (
+		SELECT 1 FROM comments pc
+		JOIN blocks b ON b.blocker_id = pc.user_id
+		WHERE pc.id = $4 AND b.blocked_id = $2
+	)
+	RETURNING id, depth, created_at, reaction_counts
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -72,13 +207,69 @@ func (s *CommentStore) Create(ctx context.Context, comment *Comment) error {
 		comment.PostID,
 		comment.UserID,
 		comment.Content,
+		comment.ParentID,
 	).Scan(
 		&comment.ID,
+		&comment.Depth,
 		&comment.CreatedAt,
+		&comment.ReactionCounts,
 	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrBlocked
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Update replaces the content of the comment and marks it as edited.
+func (s *CommentStore) Update(ctx context.Context, comment *Comment) error {
+	query := `
+	UPDATE comments SET content = $1, edited_at = NOW()
+	WHERE id = $2
+	RETURNING edited_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, comment.Content, comment.ID).Scan(&comment.EditedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the comment along with its replies and reactions.
+func (s *CommentStore) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM comments WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
 
+	if rows == 0 {
+		return ErrNotFound
+	}
+
 	return nil
 }
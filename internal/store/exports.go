@@ -0,0 +1,244 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DataExport is a user's request for an archive of their data. Key and
+// ExpiresAt are set once the archive is ready.
+type DataExport struct {
+	ID          int64   `json:"id"`
+	UserID      int64   `json:"user_id"`
+	Key         string  `json:"-"`
+	ExpiresAt   *string `json:"expires_at"`
+	CreatedAt   string  `json:"created_at"`
+	CompletedAt *string `json:"completed_at"`
+}
+
+type ExportStore struct {
+	db *sql.DB
+}
+
+// Create records a new export request. It returns ErrConflict while another
+// export of the user is still being built. An export still pending since
+// before pendingSince is taken as abandoned and replaced.
+func (s *ExportStore) Create(ctx context.Context, export *DataExport, pendingSince time.Time) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		abandoned := `
+		DELETE FROM data_exports
+		WHERE user_id = $1 AND completed_at IS NULL AND created_at <= $2
+		`
+
+		if _, err := tx.ExecContext(ctx, abandoned, export.UserID, pendingSince); err != nil {
+			return err
+		}
+
+		// a unique index allows a single pending export per user
+		query := `
+		INSERT INTO data_exports (user_id)
+		VALUES ($1)
+		RETURNING id, created_at
+		`
+
+		err := tx.QueryRowContext(ctx, query, export.UserID).Scan(&export.ID, &export.CreatedAt)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return ErrConflict
+			}
+
+			return err
+		}
+
+		return nil
+	})
+}
+
+// Complete stores the archive key and the hash of the download token.
+func (s *ExportStore) Complete(ctx context.Context, export *DataExport, token string, exp time.Duration) error {
+	query := `
+	UPDATE data_exports SET key = $1, token = $2, expiry = $3, completed_at = NOW()
+	WHERE id = $4
+	RETURNING expiry, completed_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		export.Key,
+		hashToken(token),
+		time.Now().Add(exp),
+		export.ID,
+	).Scan(
+		&export.ExpiresAt,
+		&export.CompletedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetByToken returns the unexpired export of an active user matching the
+// download token.
+func (s *ExportStore) GetByToken(ctx context.Context, token string) (*DataExport, error) {
+	query := `
+	SELECT e.id, e.user_id, e.key, e.expiry, e.created_at, e.completed_at
+	FROM data_exports e
+	JOIN users u ON u.id = e.user_id AND u.is_active = true
+	WHERE e.token = $1 AND e.expiry > $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var e DataExport
+	err := s.db.QueryRowContext(ctx, query, hashToken(token), time.Now()).Scan(
+		&e.ID,
+		&e.UserID,
+		&e.Key,
+		&e.ExpiresAt,
+		&e.CreatedAt,
+		&e.CompletedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &e, nil
+}
+
+// Delete removes an export whose archive could not be built.
+func (s *ExportStore) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM data_exports WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// DeleteExpired removes the exports that expired before now and returns the
+// keys of their archives.
+func (s *ExportStore) DeleteExpired(ctx context.Context, now time.Time) ([]string, error) {
+	query := `
+	DELETE FROM data_exports WHERE expiry <= $1
+	RETURNING key
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// GetPosts returns all posts of the user, oldest first.
+func (s *ExportStore) GetPosts(ctx context.Context, userID int64) ([]Post, error) {
+	query := `
+	SELECT id, user_id, title, content, tags, created_at, updated_at
+	FROM posts
+	WHERE user_id = $1
+	ORDER BY created_at, id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		var p Post
+		if err := rows.Scan(
+			&p.ID,
+			&p.UserID,
+			&p.Title,
+			&p.Content,
+			pq.Array(&p.Tags),
+			&p.CreatedAt,
+			&p.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+
+	return posts, rows.Err()
+}
+
+// GetComments returns all comments of the user, oldest first.
+func (s *ExportStore) GetComments(ctx context.Context, userID int64) ([]Comment, error) {
+	query := `
+	SELECT id, post_id, user_id, content, created_at
+	FROM comments
+	WHERE user_id = $1
+	ORDER BY created_at, id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []Comment{}
+	for rows.Next() {
+		var c Comment
+		if err := rows.Scan(
+			&c.ID,
+			&c.PostID,
+			&c.UserID,
+			&c.Content,
+			&c.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+
+	return comments, rows.Err()
+}
@@ -3,31 +3,284 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/lib/pq"
 )
 
+// Follower is a row of the followers table. UserID is the user doing the
+// following and FollowerID the user being followed.
 type Follower struct {
 	UserID     int64  `json:"user_id"`
 	FollowerID int64  `json:"follower_id"`
 	CreatedAt  string `json:"created_at"`
 }
 
+// FollowUser is an entry of a followers, following or follow requests list.
+type FollowUser struct {
+	ID           int64  `json:"id"`
+	Username     string `json:"username"`
+	DisplayName  string `json:"display_name"`
+	AvatarID     *int64 `json:"avatar_id"`
+	FollowedAt   string `json:"followed_at"`
+	FollowedByMe bool   `json:"followed_by_me"`
+}
+
 type FollowerStore struct {
 	db *sql.DB
 }
 
-func (s *FollowerStore) Follow(ctx context.Context, followerID int64, userID int64) error {
+// Follow makes followerID follow userID. When the account is private a
+// follow request is filed instead and pending is true. It returns ErrBlocked
+// if either user has blocked the other.
+func (s *FollowerStore) Follow(ctx context.Context, followerID int64, userID int64) (bool, error) {
 	query := `
-	INSERT INTO followers (user_id, follower_id) 
-	VALUES ($1, $2)
+	SELECT u.is_private,
+		EXISTS (
+			SELECT 1 FROM blocks
+			WHERE (blocker_id = $1 AND blocked_id = $2) OR (blocker_id = $2 AND blocked_id = $1)
+		),
+		EXISTS (SELECT 1 FROM followers WHERE user_id = $1 AND follower_id = $2)
+	FROM users u
+	WHERE u.id = $2 AND u.is_active = true
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var pending bool
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var private, blocked, following bool
+		err := tx.QueryRowContext(ctx, query, followerID, userID).Scan(&private, &blocked, &following)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		switch {
+		case blocked:
+			return ErrBlocked
+		case following:
+			return ErrConflict
+		case private && followerID != userID:
+			pending = true
+			return createFollowRequest(ctx, tx, followerID, userID)
+		}
+
+		return follow(ctx, tx, followerID, userID)
+	})
+
+	return pending, err
+}
+
+func (s *FollowerStore) Unfollow(ctx context.Context, followerID int64, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return unfollow(ctx, tx, followerID, userID)
+	})
+}
+
+// GetFollowRequests returns the pending follow requests to userID, most
+// recent first. FollowedAt is the time of the request. It pages the same way
+// as GetFollowers.
+func (s *FollowerStore) GetFollowRequests(ctx context.Context, userID int64, cq CursorQuery) ([]FollowUser, string, error) {
+	query := `
+	SELECT u.id, u.username, u.display_name, u.avatar_media_id, fr.created_at,
+		EXISTS (SELECT 1 FROM followers v WHERE v.user_id = $2 AND v.follower_id = u.id)
+	FROM follow_requests fr
+	JOIN users u ON u.id = fr.requester_id AND u.is_active = true
+	WHERE fr.user_id = $1
+		AND ($3::timestamptz IS NULL OR (fr.created_at, fr.requester_id) < ($3, $4))
+	ORDER BY fr.created_at DESC, fr.requester_id DESC
+	LIMIT $5
+	`
+
+	return s.list(ctx, query, userID, userID, cq)
+}
+
+// ApproveFollowRequest turns the request of requesterID into a follow of
+// userID.
+func (s *FollowerStore) ApproveFollowRequest(ctx context.Context, userID, requesterID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := deleteFollowRequest(ctx, tx, requesterID, userID); err != nil {
+			return err
+		}
+
+		return follow(ctx, tx, requesterID, userID)
+	})
+}
+
+func (s *FollowerStore) RejectFollowRequest(ctx context.Context, userID, requesterID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return deleteFollowRequest(ctx, tx, requesterID, userID)
+	})
+}
+
+// IsFollowing reports whether followerID follows userID.
+func (s *FollowerStore) IsFollowing(ctx context.Context, followerID int64, userID int64) (bool, error) {
+	query := `
+	SELECT EXISTS (SELECT 1 FROM followers WHERE user_id = $1 AND follower_id = $2)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var following bool
+	err := s.db.QueryRowContext(ctx, query, followerID, userID).Scan(&following)
+
+	return following, err
+}
+
+// GetFollowers returns the users following userID, most recent first, and the
+// cursor of the next page, which is empty on the last page. FollowedByMe is
+// set for the users viewerID follows.
+func (s *FollowerStore) GetFollowers(ctx context.Context, userID, viewerID int64, cq CursorQuery) ([]FollowUser, string, error) {
+	query := `
+	SELECT u.id, u.username, u.display_name, u.avatar_media_id, f.created_at,
+		EXISTS (SELECT 1 FROM followers v WHERE v.user_id = $2 AND v.follower_id = u.id)
+	FROM followers f
+	JOIN users u ON u.id = f.user_id AND u.is_active = true
+	WHERE f.follower_id = $1
+		AND ($3::timestamptz IS NULL OR (f.created_at, f.user_id) < ($3, $4))
+	ORDER BY f.created_at DESC, f.user_id DESC
+	LIMIT $5
+	`
+
+	return s.list(ctx, query, userID, viewerID, cq)
+}
+
+// GetFollowing returns the users userID follows, most recent first. It pages
+// the same way as GetFollowers.
+func (s *FollowerStore) GetFollowing(ctx context.Context, userID, viewerID int64, cq CursorQuery) ([]FollowUser, string, error) {
+	query := `
+	SELECT u.id, u.username, u.display_name, u.avatar_media_id, f.created_at,
+		EXISTS (SELECT 1 FROM followers v WHERE v.user_id = $2 AND v.follower_id = u.id)
+	FROM followers f
+	JOIN users u ON u.id = f.follower_id AND u.is_active = true
+	WHERE f.user_id = $1
+		AND ($3::timestamptz IS NULL OR (f.created_at, f.follower_id) < ($3, $4))
+	ORDER BY f.created_at DESC, f.follower_id DESC
+	LIMIT $5
 	`
 
+	return s.list(ctx, query, userID, viewerID, cq)
+}
+
+func (s *FollowerStore) list(ctx context.Context, query string, userID, viewerID int64, cq CursorQuery) ([]FollowUser, string, error) {
+	var afterTime *string
+	var afterID int64
+	if cq.After != nil {
+		afterTime, afterID = &cq.After.CreatedAt, cq.After.ID
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := s.db.ExecContext(ctx, query, followerID, userID)
+	// one extra row tells whether there is a next page
+	rows, err := s.db.QueryContext(ctx, query, userID, viewerID, afterTime, afterID, cq.Limit+1)
 	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	users := []FollowUser{}
+	for rows.Next() {
+		var u FollowUser
+		if err := rows.Scan(
+			&u.ID,
+			&u.Username,
+			&u.DisplayName,
+			&u.AvatarID,
+			&u.FollowedAt,
+			&u.FollowedByMe,
+		); err != nil {
+			return nil, "", err
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(users) > cq.Limit {
+		users = users[:cq.Limit]
+		last := users[len(users)-1]
+		next = Cursor{CreatedAt: last.FollowedAt, ID: last.ID}.Encode()
+	}
+
+	return users, next, nil
+}
+
+func follow(ctx context.Context, tx *sql.Tx, followerID, userID int64) error {
+	query := `
+	INSERT INTO followers (user_id, follower_id)
+	VALUES ($1, $2)
+	`
+
+	if _, err := tx.ExecContext(ctx, query, followerID, userID); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return ErrConflict
+			case "23503":
+				return ErrNotFound
+			}
+		}
+
+		return err
+	}
+
+	return updateFollowCounts(ctx, tx, followerID, userID, 1)
+}
+
+// unfollow removes the follow, or the pending follow request, of followerID.
+func unfollow(ctx context.Context, tx *sql.Tx, followerID, userID int64) error {
+	query := `
+	DELETE FROM followers WHERE user_id = $1 AND follower_id = $2
+	`
+
+	res, err := tx.ExecContext(ctx, query, followerID, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		if err := deleteFollowRequest(ctx, tx, followerID, userID); err != nil && err != ErrNotFound {
+			return err
+		}
+		return nil
+	}
+
+	return updateFollowCounts(ctx, tx, followerID, userID, -1)
+}
+
+func createFollowRequest(ctx context.Context, tx *sql.Tx, requesterID, userID int64) error {
+	query := `
+	INSERT INTO follow_requests (requester_id, user_id)
+	VALUES ($1, $2)
+	`
+
+	if _, err := tx.ExecContext(ctx, query, requesterID, userID); err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
 			return ErrConflict
 		}
@@ -38,14 +291,52 @@ func (s *FollowerStore) Follow(ctx context.Context, followerID int64, userID int
 	return nil
 }
 
-func (s *FollowerStore) Unfollow(ctx context.Context, followerID int64, userID int64) error {
+func deleteFollowRequest(ctx context.Context, tx *sql.Tx, requesterID, userID int64) error {
 	query := `
-	DELETE FROM followers WHERE user_id = $1 AND follower_id = $2
+	DELETE FROM follow_requests WHERE requester_id = $1 AND user_id = $2
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
+	res, err := tx.ExecContext(ctx, query, requesterID, userID)
+	if err != nil {
+		return err
+	}
 
-	_, err := s.db.ExecContext(ctx, query, followerID, userID)
-	return err
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// updateFollowCounts adjusts the denormalized counters of both users by
+// delta. The rows are updated one at a time in order of their IDs, so two
+// users following each other at once cannot deadlock on them.
+func updateFollowCounts(ctx context.Context, tx *sql.Tx, followerID, userID int64, delta int) error {
+	followingQuery := `UPDATE users SET following_count = following_count + $2 WHERE id = $1`
+	followerQuery := `UPDATE users SET follower_count = follower_count + $2 WHERE id = $1`
+
+	updates := []struct {
+		query string
+		id    int64
+	}{
+		{followingQuery, followerID},
+		{followerQuery, userID},
+	}
+
+	if userID < followerID {
+		updates[0], updates[1] = updates[1], updates[0]
+	}
+
+	for _, u := range updates {
+		if _, err := tx.ExecContext(ctx, u.query, u.id, delta); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// Identity links an account at an external OpenID Connect provider to a user.
+type Identity struct {
+	Provider  string `json:"provider"`
+	Subject   string `json:"subject"`
+	UserID    int64  `json:"user_id"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"created_at"`
+}
+
+type IdentityStore struct {
+	db *sql.DB
+}
+
+// GetUserID returns the active user linked to the provider subject.
+func (s *IdentityStore) GetUserID(ctx context.Context, provider, subject string) (int64, error) {
+	query := `
+	SELECT u.id FROM identities i
+	JOIN users u ON u.id = i.user_id
+	WHERE i.provider = $1 AND i.subject = $2 AND u.is_active = true
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var userID int64
+	err := s.db.QueryRowContext(ctx, query, provider, subject).Scan(&userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return userID, nil
+}
+
+func (s *IdentityStore) Create(ctx context.Context, identity *Identity) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return createIdentity(ctx, tx, identity)
+	})
+}
+
+func createIdentity(ctx context.Context, tx *sql.Tx, identity *Identity) error {
+	query := `
+	INSERT INTO identities (provider, subject, user_id, email)
+	VALUES ($1, $2, $3, $4)
+	RETURNING created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := tx.QueryRowContext(
+		ctx,
+		query,
+		identity.Provider,
+		identity.Subject,
+		identity.UserID,
+		identity.Email,
+	).Scan(&identity.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrConflict
+		}
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,285 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// Media is an uploaded image. The blobs live in the media BlobStore under Key
+// and ThumbnailKey.
+type Media struct {
+	ID           int64  `json:"id"`
+	UserID       int64  `json:"user_id"`
+	Key          string `json:"-"`
+	ThumbnailKey string `json:"-"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type MediaStore struct {
+	db *sql.DB
+}
+
+// Create stores the media unless it would take the user's uploads over quota
+// bytes, in which case ErrQuotaExceeded is returned.
+func (s *MediaStore) Create(ctx context.Context, media *Media, quota int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		// concurrent uploads of the user wait for each other so each one sums
+		// the uploads stored before it. The lock doesn't block rows referencing
+		// the user.
+		lock := `SELECT id FROM users WHERE id = $1 FOR NO KEY UPDATE`
+
+		var userID int64
+		if err := tx.QueryRowContext(ctx, lock, media.UserID).Scan(&userID); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		query := `
+		INSERT INTO media (user_id, key, thumbnail_key, content_type, size, width, height)
+		SELECT $1, $2, $3, $4, $5, $6, $7
+		WHERE (SELECT COALESCE(SUM(size), 0) FROM media WHERE user_id = $1) + $5 <= $8
+		RETURNING id, created_at
+		`
+
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			media.UserID,
+			media.Key,
+			media.ThumbnailKey,
+			media.ContentType,
+			media.Size,
+			media.Width,
+			media.Height,
+			quota,
+		).Scan(
+			&media.ID,
+			&media.CreatedAt,
+		)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrQuotaExceeded
+			default:
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *MediaStore) GetByID(ctx context.Context, id int64) (*Media, error) {
+	query := `
+	SELECT id, user_id, key, thumbnail_key, content_type, size, width, height, created_at
+	FROM media WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	media := &Media{}
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&media.ID,
+		&media.UserID,
+		&media.Key,
+		&media.ThumbnailKey,
+		&media.ContentType,
+		&media.Size,
+		&media.Width,
+		&media.Height,
+		&media.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return media, nil
+}
+
+// GetUsage returns the total size in bytes of the user's uploads.
+func (s *MediaStore) GetUsage(ctx context.Context, userID int64) (int64, error) {
+	query := `SELECT COALESCE(SUM(size), 0) FROM media WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var usage int64
+	if err := s.db.QueryRowContext(ctx, query, userID).Scan(&usage); err != nil {
+		return 0, err
+	}
+
+	return usage, nil
+}
+
+// Delete removes one of the user's uploads and returns it so its blobs can be
+// deleted as well.
+func (s *MediaStore) Delete(ctx context.Context, id, userID int64) (*Media, error) {
+	query := `
+	DELETE FROM media WHERE id = $1 AND user_id = $2
+	RETURNING id, user_id, key, thumbnail_key, content_type, size, width, height, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	media := &Media{}
+	err := s.db.QueryRowContext(ctx, query, id, userID).Scan(
+		&media.ID,
+		&media.UserID,
+		&media.Key,
+		&media.ThumbnailKey,
+		&media.ContentType,
+		&media.Size,
+		&media.Width,
+		&media.Height,
+		&media.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return media, nil
+}
+
+// attachMedia links the user's uploads to a post in the given order. It
+// returns ErrNotFound if any of them does not exist or belongs to someone else.
+func attachMedia(ctx context.Context, tx *sql.Tx, postID, userID int64, media []Media) error {
+	query := `
+	WITH m AS (
+		SELECT id, content_type, size, width, height, created_at
+		FROM media WHERE id = $2 AND user_id = $4
+	), attached AS (
+		INSERT INTO post_media (post_id, media_id, position)
+		SELECT $1, id, $3 FROM m
+	)
+	SELECT content_type, size, width, height, created_at FROM m
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	for i := range media {
+		m := &media[i]
+		err := tx.QueryRowContext(ctx, query, postID, m.ID, i, userID).Scan(
+			&m.ContentType,
+			&m.Size,
+			&m.Width,
+			&m.Height,
+			&m.CreatedAt,
+		)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+		m.UserID = userID
+	}
+
+	return nil
+}
+
+func getPostMedia(ctx context.Context, db *sql.DB, postID int64) ([]Media, error) {
+	query := `
+	SELECT m.id, m.user_id, m.key, m.thumbnail_key, m.content_type, m.size, m.width, m.height, m.created_at
+	FROM post_media pm
+	JOIN media m ON m.id = pm.media_id
+	WHERE pm.post_id = $1
+	ORDER BY pm.position
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	media := []Media{}
+	for rows.Next() {
+		var m Media
+		err := rows.Scan(
+			&m.ID,
+			&m.UserID,
+			&m.Key,
+			&m.ThumbnailKey,
+			&m.ContentType,
+			&m.Size,
+			&m.Width,
+			&m.Height,
+			&m.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		media = append(media, m)
+	}
+
+	return media, rows.Err()
+}
+
+func getUserMedia(ctx context.Context, tx *sql.Tx, userID int64) ([]Media, error) {
+	query := `
+	SELECT id, user_id, key, thumbnail_key, content_type, size, width, height, created_at
+	FROM media
+	WHERE user_id = $1
+	`
+
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	media := []Media{}
+	for rows.Next() {
+		var m Media
+		err := rows.Scan(
+			&m.ID,
+			&m.UserID,
+			&m.Key,
+			&m.ThumbnailKey,
+			&m.ContentType,
+			&m.Size,
+			&m.Width,
+			&m.Height,
+			&m.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		media = append(media, m)
+	}
+
+	return media, rows.Err()
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+type MFAStore struct {
+	db *sql.DB
+}
+
+// SetTOTPSecret stores a pending secret; it is not enforced until
+// EnableTOTP is called.
+func (s *MFAStore) SetTOTPSecret(ctx context.Context, userID int64, secret string) error {
+	query := `UPDATE users SET totp_secret = $1, totp_enabled = false, totp_last_step = 0 WHERE id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, secret, userID)
+	return err
+}
+
+// EnableTOTP turns on 2FA and replaces the user's recovery codes.
+func (s *MFAStore) EnableTOTP(ctx context.Context, userID int64, recoveryCodes []string) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `UPDATE users SET totp_enabled = true WHERE id = $1 AND totp_secret IS NOT NULL`
+		res, err := tx.ExecContext(ctx, query, userID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		if err := s.deleteRecoveryCodes(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		for _, code := range recoveryCodes {
+			query := `INSERT INTO user_recovery_codes (code, user_id) VALUES ($1, $2)`
+			if _, err := tx.ExecContext(ctx, query, hashToken(code), userID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *MFAStore) DisableTOTP(ctx context.Context, userID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `UPDATE users SET totp_secret = NULL, totp_enabled = false, totp_last_step = 0 WHERE id = $1`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return err
+		}
+
+		return s.deleteRecoveryCodes(ctx, tx, userID)
+	})
+}
+
+// UseTOTPStep records the time step of an accepted code. It returns
+// ErrConflict when that step, or a later one, was already used so a code
+// cannot be replayed.
+func (s *MFAStore) UseTOTPStep(ctx context.Context, userID int64, step int64) error {
+	query := `UPDATE users SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrConflict
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used, or returns
+// ErrNotFound.
+func (s *MFAStore) UseRecoveryCode(ctx context.Context, userID int64, code string) error {
+	query := `
+	UPDATE user_recovery_codes SET used_at = NOW()
+	WHERE user_id = $1 AND code = $2 AND used_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, userID, hashToken(code))
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MFAStore) deleteRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int64) error {
+	query := `DELETE FROM user_recovery_codes WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, userID)
+	return err
+}
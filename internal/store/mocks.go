@@ -8,7 +8,19 @@ import (
 
 func NewMockStore() Storage {
 	return Storage{
-		Users: &MockUserStore{},
+		Posts:      &MockPostStore{},
+		Users:      &MockUserStore{},
+		Comments:   &MockCommentStore{},
+		Sessions:   &MockSessionStore{},
+		MFA:        &MockMFAStore{},
+		APIKeys:    &MockAPIKeyStore{},
+		Identities: &MockIdentityStore{},
+		Media:      &MockMediaStore{},
+		Followers:  &MockFollowerStore{},
+		Blocks:     &MockBlockStore{},
+		Mutes:      &MockMuteStore{},
+		Reactions:  &MockReactionStore{},
+		Exports:    &MockExportStore{},
 	}
 }
 
@@ -24,7 +36,7 @@ func (m *MockUserStore) GetByID(ctx context.Context, userID int64) (*User, error
 }
 
 func (m *MockUserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
-	return nil, nil
+	return nil, ErrNotFound
 }
 
 func (m *MockUserStore) CreateAndInvite(ctx context.Context, user *User, token string, exp time.Duration) error {
@@ -38,3 +50,403 @@ func (m *MockUserStore) Activate(ctx context.Context, token string) error {
 func (m *MockUserStore) Delete(ctx context.Context, id int64) error {
 	return nil
 }
+
+func (m *MockUserStore) CreatePasswordReset(ctx context.Context, userID int64, token string, exp time.Duration) error {
+	return nil
+}
+
+func (m *MockUserStore) ResetPassword(ctx context.Context, token, newPassword string) (int64, error) {
+	return 1, nil
+}
+
+func (m *MockUserStore) ReissueInvitation(ctx context.Context, email, token string, exp time.Duration) (*User, error) {
+	return nil, ErrNotFound
+}
+
+func (m *MockUserStore) DeleteUnactivated(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockUserStore) CreateEmailChange(ctx context.Context, userID int64, newEmail, token string, exp time.Duration) error {
+	return nil
+}
+
+func (m *MockUserStore) ConfirmEmailChange(ctx context.Context, token string) (*User, string, error) {
+	return nil, "", ErrNotFound
+}
+
+func (m *MockUserStore) UpdateProfile(ctx context.Context, user *User) error {
+	return nil
+}
+
+func (m *MockUserStore) CanViewPosts(ctx context.Context, authorID, viewerID int64) (bool, error) {
+	return true, nil
+}
+
+func (m *MockUserStore) GetDeactivatedByEmail(ctx context.Context, email string) (*User, error) {
+	return nil, ErrNotFound
+}
+
+func (m *MockUserStore) GetDeactivatedByID(ctx context.Context, id int64) (*User, error) {
+	return nil, ErrNotFound
+}
+
+func (m *MockUserStore) Deactivate(ctx context.Context, userID int64, deleteAfter time.Time) error {
+	return nil
+}
+
+func (m *MockUserStore) Reactivate(ctx context.Context, userID int64) error {
+	return nil
+}
+
+func (m *MockUserStore) GetDueForDeletion(ctx context.Context, now time.Time, limit int) ([]int64, error) {
+	return nil, nil
+}
+
+func (m *MockUserStore) Purge(ctx context.Context, userID int64, anonymize bool) ([]Media, error) {
+	return nil, nil
+}
+
+func (m *MockUserStore) Search(ctx context.Context, viewerID int64, sq UserSearchQuery) ([]UserSearchResult, string, error) {
+	return []UserSearchResult{}, "", nil
+}
+
+func (m *MockUserStore) CreateWithIdentity(ctx context.Context, user *User, identity *Identity) error {
+	user.ID = 1
+	identity.UserID = user.ID
+	return nil
+}
+
+// MockRefreshToken is the only refresh token MockSessionStore can rotate;
+// MockReusedRefreshToken behaves like a token that was already used.
+const (
+	MockRefreshToken       = "test-refresh-token"
+	MockReusedRefreshToken = "test-reused-refresh-token"
+)
+
+type MockSessionStore struct {
+}
+
+func (m *MockSessionStore) Create(ctx context.Context, session *Session, token string, exp time.Duration) error {
+	return nil
+}
+
+func (m *MockSessionStore) GetByID(ctx context.Context, id string) (*Session, error) {
+	return &Session{ID: id, UserID: 1}, nil
+}
+
+func (m *MockSessionStore) GetByRefreshToken(ctx context.Context, token string) (*Session, error) {
+	if token != MockRefreshToken && token != MockReusedRefreshToken {
+		return nil, ErrNotFound
+	}
+
+	return &Session{ID: "test-session", UserID: 1}, nil
+}
+
+func (m *MockSessionStore) Rotate(ctx context.Context, token, newToken string, exp time.Duration) (*Session, error) {
+	switch token {
+	case MockRefreshToken:
+		return &Session{ID: "test-session", UserID: 1}, nil
+	case MockReusedRefreshToken:
+		return &Session{ID: "test-session", UserID: 1}, ErrTokenReused
+	default:
+		return nil, ErrNotFound
+	}
+}
+
+func (m *MockSessionStore) Revoke(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *MockSessionStore) RevokeAllForUser(ctx context.Context, userID int64) error {
+	return nil
+}
+
+func (m *MockSessionStore) GetByUserID(ctx context.Context, userID int64) ([]Session, error) {
+	return []Session{{ID: "test-session", UserID: userID}}, nil
+}
+
+func (m *MockSessionStore) Touch(ctx context.Context, session *Session, ip string) error {
+	session.IP = ip
+	return nil
+}
+
+func (m *MockSessionStore) RevokeForUser(ctx context.Context, id string, userID int64) error {
+	return nil
+}
+
+type MockMFAStore struct {
+}
+
+func (m *MockMFAStore) SetTOTPSecret(ctx context.Context, userID int64, secret string) error {
+	return nil
+}
+
+func (m *MockMFAStore) EnableTOTP(ctx context.Context, userID int64, recoveryCodes []string) error {
+	return nil
+}
+
+func (m *MockMFAStore) DisableTOTP(ctx context.Context, userID int64) error {
+	return nil
+}
+
+func (m *MockMFAStore) UseTOTPStep(ctx context.Context, userID int64, step int64) error {
+	return nil
+}
+
+func (m *MockMFAStore) UseRecoveryCode(ctx context.Context, userID int64, code string) error {
+	return ErrNotFound
+}
+
+// MockAPIKeyToken is the only key MockAPIKeyStore accepts; it belongs to
+// user 1 and may only read users.
+const MockAPIKeyToken = "test-api-key"
+
+type MockAPIKeyStore struct {
+}
+
+func (m *MockAPIKeyStore) Create(ctx context.Context, key *APIKey, token string, expiry *time.Time) error {
+	return nil
+}
+
+func (m *MockAPIKeyStore) GetByUserID(ctx context.Context, userID int64) ([]APIKey, error) {
+	return []APIKey{}, nil
+}
+
+func (m *MockAPIKeyStore) GetByToken(ctx context.Context, token string) (*APIKey, error) {
+	if token != MockAPIKeyToken {
+		return nil, ErrNotFound
+	}
+
+	return &APIKey{ID: 1, UserID: 1, Scopes: []string{"users:read"}}, nil
+}
+
+func (m *MockAPIKeyStore) Touch(ctx context.Context, key *APIKey) error {
+	return nil
+}
+
+func (m *MockAPIKeyStore) Delete(ctx context.Context, id, userID int64) error {
+	return nil
+}
+
+type MockIdentityStore struct {
+}
+
+func (m *MockIdentityStore) GetUserID(ctx context.Context, provider, subject string) (int64, error) {
+	return 0, ErrNotFound
+}
+
+func (m *MockIdentityStore) Create(ctx context.Context, identity *Identity) error {
+	return nil
+}
+
+type MockMediaStore struct {
+}
+
+func (m *MockMediaStore) Create(ctx context.Context, media *Media, quota int64) error {
+	if media.Size > quota {
+		return ErrQuotaExceeded
+	}
+	media.ID = 1
+	return nil
+}
+
+func (m *MockMediaStore) GetByID(ctx context.Context, id int64) (*Media, error) {
+	return nil, ErrNotFound
+}
+
+func (m *MockMediaStore) GetUsage(ctx context.Context, userID int64) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockMediaStore) Delete(ctx context.Context, id, userID int64) (*Media, error) {
+	return nil, ErrNotFound
+}
+
+type MockFollowerStore struct {
+}
+
+func (m *MockFollowerStore) Follow(ctx context.Context, followerID int64, userID int64) (bool, error) {
+	return false, nil
+}
+
+func (m *MockFollowerStore) Unfollow(ctx context.Context, followerID int64, userID int64) error {
+	return nil
+}
+
+func (m *MockFollowerStore) IsFollowing(ctx context.Context, followerID int64, userID int64) (bool, error) {
+	return false, nil
+}
+
+func (m *MockFollowerStore) GetFollowers(ctx context.Context, userID, viewerID int64, cq CursorQuery) ([]FollowUser, string, error) {
+	return []FollowUser{}, "", nil
+}
+
+func (m *MockFollowerStore) GetFollowing(ctx context.Context, userID, viewerID int64, cq CursorQuery) ([]FollowUser, string, error) {
+	return []FollowUser{}, "", nil
+}
+
+func (m *MockFollowerStore) GetFollowRequests(ctx context.Context, userID int64, cq CursorQuery) ([]FollowUser, string, error) {
+	return []FollowUser{}, "", nil
+}
+
+func (m *MockFollowerStore) ApproveFollowRequest(ctx context.Context, userID, requesterID int64) error {
+	return ErrNotFound
+}
+
+func (m *MockFollowerStore) RejectFollowRequest(ctx context.Context, userID, requesterID int64) error {
+	return ErrNotFound
+}
+
+func (m *MockFollowerStore) GetSuggestions(ctx context.Context, userID int64, limit int) ([]Suggestion, error) {
+	return []Suggestion{{ID: 2, Username: "gopher", MutualCount: 1}}, nil
+}
+
+type MockBlockStore struct {
+}
+
+func (m *MockBlockStore) Block(ctx context.Context, blockerID, userID int64) error {
+	return nil
+}
+
+func (m *MockBlockStore) Unblock(ctx context.Context, blockerID, userID int64) error {
+	return nil
+}
+
+type MockMuteStore struct {
+}
+
+func (m *MockMuteStore) Mute(ctx context.Context, muterID, userID int64) error {
+	return nil
+}
+
+func (m *MockMuteStore) Unmute(ctx context.Context, muterID, userID int64) error {
+	return nil
+}
+
+type MockExportStore struct {
+}
+
+func (m *MockExportStore) Create(ctx context.Context, export *DataExport, pendingSince time.Time) error {
+	export.ID = 1
+	return nil
+}
+
+func (m *MockExportStore) Complete(ctx context.Context, export *DataExport, token string, exp time.Duration) error {
+	return nil
+}
+
+func (m *MockExportStore) GetByToken(ctx context.Context, token string) (*DataExport, error) {
+	return nil, ErrNotFound
+}
+
+func (m *MockExportStore) Delete(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *MockExportStore) DeleteExpired(ctx context.Context, now time.Time) ([]string, error) {
+	return nil, nil
+}
+
+func (m *MockExportStore) GetPosts(ctx context.Context, userID int64) ([]Post, error) {
+	return []Post{}, nil
+}
+
+func (m *MockExportStore) GetComments(ctx context.Context, userID int64) ([]Comment, error) {
+	return []Comment{}, nil
+}
+
+type MockPostStore struct {
+}
+
+func (m *MockPostStore) Create(ctx context.Context, post *Post) error {
+	return nil
+}
+
+func (m *MockPostStore) GetByID(ctx context.Context, postID int64) (*Post, error) {
+	return &Post{ID: postID, UserID: 1, Version: 1, ReactionCounts: ReactionCounts{}}, nil
+}
+
+func (m *MockPostStore) Delete(ctx context.Context, postID int64) error {
+	return nil
+}
+
+func (m *MockPostStore) Update(ctx context.Context, post *Post) error {
+	if post.Version != 1 {
+		return ErrNotFound
+	}
+
+	post.Version++
+	return nil
+}
+
+func (m *MockPostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+func (m *MockPostStore) GetUserPosts(ctx context.Context, authorID, viewerID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+func (m *MockPostStore) Repost(ctx context.Context, post *Post) error {
+	post.ID = 2
+	return nil
+}
+
+func (m *MockPostStore) Unrepost(ctx context.Context, userID, postID int64) error {
+	return nil
+}
+
+func (m *MockPostStore) GetRevisions(ctx context.Context, postID int64) ([]PostRevision, error) {
+	return []PostRevision{{PostID: postID, Version: 0, Title: "title", Content: "the old content"}}, nil
+}
+
+func (m *MockPostStore) GetRevision(ctx context.Context, postID int64, version int) (*PostRevision, error) {
+	if version != 0 {
+		return nil, ErrNotFound
+	}
+
+	return &PostRevision{PostID: postID, Version: 0, Title: "title", Content: "the old content"}, nil
+}
+
+type MockCommentStore struct {
+}
+
+func (m *MockCommentStore) GetByID(ctx context.Context, commentID int64) (*Comment, error) {
+	return &Comment{ID: commentID, PostID: 1, UserID: 1, User: User{ID: 1, Username: "gopher"}, ReactionCounts: ReactionCounts{}}, nil
+}
+
+func (m *MockCommentStore) GetByPostID(ctx context.Context, postID, viewerID int64, cq CursorQuery) ([]Comment, string, error) {
+	return []Comment{}, "", nil
+}
+
+func (m *MockCommentStore) GetReplies(ctx context.Context, commentID, viewerID int64, cq CursorQuery) ([]Comment, string, error) {
+	return []Comment{}, "", nil
+}
+
+func (m *MockCommentStore) Create(ctx context.Context, comment *Comment) error {
+	return nil
+}
+
+func (m *MockCommentStore) Update(ctx context.Context, comment *Comment) error {
+	return nil
+}
+
+func (m *MockCommentStore) Delete(ctx context.Context, commentID int64) error {
+	return nil
+}
+
+type MockReactionStore struct {
+}
+
+func (m *MockReactionStore) React(ctx context.Context, target ReactionTarget, targetID, userID int64, emoji string) error {
+	return nil
+}
+
+func (m *MockReactionStore) Unreact(ctx context.Context, target ReactionTarget, targetID, userID int64) error {
+	return nil
+}
+
+func (m *MockReactionStore) Get(ctx context.Context, target ReactionTarget, targetID, userID int64) (string, error) {
+	return "", ErrNotFound
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// MuteStore keeps muted users. Unlike a block, a mute is invisible to the
+// muted user and only filters the muter's feed and comment listings.
+type MuteStore struct {
+	db *sql.DB
+}
+
+func (s *MuteStore) Mute(ctx context.Context, muterID, userID int64) error {
+	query := `
+	INSERT INTO mutes (muter_id, muted_id)
+	VALUES ($1, $2)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, muterID, userID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return ErrConflict
+			case "23503":
+				return ErrNotFound
+			}
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *MuteStore) Unmute(ctx context.Context, muterID, userID int64) error {
+	query := `
+	DELETE FROM mutes WHERE muter_id = $1 AND muted_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, muterID, userID)
+	return err
+}
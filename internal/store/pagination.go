@@ -1,12 +1,16 @@
 package store
 
 import (
+	"encoding/base64"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 )
 
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 type PaginatedFeedQuery struct {
 	Limit  int      `json:"limit" validate:"gte=1,lte=20"`
 	Offset int      `json:"offset" validate:"gte=0"`
@@ -73,3 +77,134 @@ func parseTime(s string) string {
 	}
 	return t.Format(time.RFC3339)
 }
+
+// CursorQuery pages through a list ordered newest first. After is decoded
+// from the opaque cursor returned with the previous page.
+type CursorQuery struct {
+	Limit int `json:"limit" validate:"gte=1,lte=100"`
+	After *Cursor
+}
+
+// Cursor is the position of the last item of a page.
+type Cursor struct {
+	CreatedAt string
+	ID        int64
+}
+
+func (cq CursorQuery) Parse(r *http.Request) (CursorQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return cq, err
+		}
+		cq.Limit = l
+	}
+
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return cq, err
+		}
+		cq.After = c
+	}
+
+	return cq, nil
+}
+
+func (c Cursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.CreatedAt + "|" + strconv.FormatInt(c.ID, 10)))
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, id, ok := strings.Cut(string(data), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	if _, err := time.Parse(time.RFC3339, createdAt); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	c := Cursor{CreatedAt: createdAt}
+	if c.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// UserSearchQuery pages through search results ordered by rank. After is
+// decoded from the opaque cursor returned with the previous page.
+type UserSearchQuery struct {
+	Query string `json:"q" validate:"required,max=100"`
+	Limit int    `json:"limit" validate:"gte=1,lte=50"`
+	After *RankCursor
+}
+
+// RankCursor is the position of the last result of a page.
+type RankCursor struct {
+	Rank float64
+	ID   int64
+}
+
+func (sq UserSearchQuery) Parse(r *http.Request) (UserSearchQuery, error) {
+	qs := r.URL.Query()
+
+	sq.Query = strings.TrimSpace(qs.Get("q"))
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return sq, err
+		}
+		sq.Limit = l
+	}
+
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		c, err := DecodeRankCursor(cursor)
+		if err != nil {
+			return sq, err
+		}
+		sq.After = c
+	}
+
+	return sq, nil
+}
+
+func (c RankCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatFloat(c.Rank, 'g', -1, 64) + "|" + strconv.FormatInt(c.ID, 10)))
+}
+
+func DecodeRankCursor(s string) (*RankCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	rank, id, ok := strings.Cut(string(data), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	var c RankCursor
+	if c.Rank, err = strconv.ParseFloat(rank, 64); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if c.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
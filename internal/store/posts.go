@@ -10,16 +10,28 @@ import (
 )
 
 type Post struct {
-	ID        int64     `json:"id"`
-	Content   string    `json:"content"`
-	Title     string    `json:"title"`
-	UserID    int64     `json:"user_id"`
-	Tags      []string  `json:"tags"`
-	CreatedAt string    `json:"created_at"`
-	UpdatedAt string    `json:"updated_at"`
-	Version   int       `json:"version"`
-	Comments  []Comment `json:"comments"`
-	User      User      `json:"user"`
+	ID             int64          `json:"id"`
+	Content        string         `json:"content"`
+	Title          string         `json:"title"`
+	UserID         int64          `json:"user_id"`
+	Tags           []string       `json:"tags"`
+	CreatedAt      string         `json:"created_at"`
+	UpdatedAt      string         `json:"updated_at"`
+	Version        int            `json:"version"`
+	Comments       []Comment      `json:"comments"`
+	User           User           `json:"user"`
+	Media          []Media        `json:"media"`
+	ReactionCounts ReactionCounts `json:"reaction_counts"`
+	// MyReaction is the emoji the authenticated user reacted with, if any
+	MyReaction *string `json:"my_reaction"`
+	RepostOfID *int64  `json:"repost_of_id"`
+	QuoteOfID  *int64  `json:"quote_of_id"`
+	// IsQuote stays set after the quoted post is deleted
+	IsQuote  bool          `json:"is_quote"`
+	Original *OriginalPost `json:"original,omitempty"`
+	// NextCommentsCursor fetches the comments after the first page embedded
+	// in Comments
+	NextCommentsCursor string `json:"next_comments_cursor,omitempty"`
 }
 
 type PostWithMetadata struct {
@@ -31,45 +43,72 @@ type PostStore struct {
 	db *sql.DB
 }
 
+// Create stores the post and attaches post.Media, which only need their IDs
+// set and must belong to the author. When post.QuoteOfID is set the post
+// quotes that post, or its original if it is a repost. Create returns
+// ErrQuoteNotFound if there is no such post, and ErrBlocked or
+// ErrPrivatePost if the author cannot share it.
 func (s *PostStore) Create(ctx context.Context, post *Post) error {
-	query := `INSERT INTO posts (content, title, user_id, tags)
-	VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at`
-
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `INSERT INTO posts (content, title, user_id, tags, quote_of_id, is_quote)
+		VALUES ($1, $2, $3, $4, $5, $5 IS NOT NULL) RETURNING id, created_at, updated_at, reaction_counts`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if post.QuoteOfID != nil {
+			originalID, err := shareableOriginal(ctx, tx, *post.QuoteOfID, post.UserID)
+			if err != nil {
+				if err == ErrNotFound {
+					return ErrQuoteNotFound
+				}
+				return err
+			}
+
+			post.QuoteOfID = &originalID
+			post.IsQuote = true
+		}
 
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		post.Content,
-		post.Title,
-		post.UserID,
-		pq.Array(post.Tags),
-	).Scan(
-		&post.ID,
-		&post.CreatedAt,
-		&post.UpdatedAt,
-	)
-	if err != nil {
-		return err
-	}
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			post.Content,
+			post.Title,
+			post.UserID,
+			pq.Array(post.Tags),
+			post.QuoteOfID,
+		).Scan(
+			&post.ID,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+			&post.ReactionCounts,
+		)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		return attachMedia(ctx, tx, post.ID, post.UserID, post.Media)
+	})
 }
 
 func (s *PostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
+	// the original is shown whenever its author's posts are, callers check
+	// that the viewer may see it
 	query := `
-		SELECT id, content, title, user_id, tags, created_at, updated_at, version 
-		FROM posts 
-		WHERE id = $1
+		SELECT p.id, p.content, p.title, p.user_id, p.tags, p.created_at, p.updated_at, p.version, p.reaction_counts,
+			p.repost_of_id, p.quote_of_id, p.is_quote,
+			` + originalPostColumns("COALESCE("+authorShown("ou")+", false)") + `
+		FROM posts p` + originalPostJoins + `
+		WHERE p.id = $1
 		`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
 	var post Post
+	var original originalPostRow
 
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
+	err := s.db.QueryRowContext(ctx, query, id).Scan(append([]any{
 		&post.ID,
 		&post.Content,
 		&post.Title,
@@ -78,7 +117,11 @@ func (s *PostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
 		&post.CreatedAt,
 		&post.UpdatedAt,
 		&post.Version,
-	)
+		&post.ReactionCounts,
+		&post.RepostOfID,
+		&post.QuoteOfID,
+		&post.IsQuote,
+	}, original.dest()...)...)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -88,6 +131,13 @@ func (s *PostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
 		}
 	}
 
+	post.Original = original.original(&post)
+
+	post.Media, err = getPostMedia(ctx, s.db, post.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	return &post, nil
 }
 
@@ -114,53 +164,126 @@ func (s *PostStore) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
+// Update stores the new title and content of the post if it is still at
+// post.Version, keeping the replaced version as a revision. It returns
+// ErrNotFound if the post was deleted or edited in the meantime.
 func (s *PostStore) Update(ctx context.Context, post *Post) error {
-	query := `UPDATE posts 
-	SET content = $1, title = $2, version = version + 1
-	WHERE id = $3 AND version = $4
-	RETURNING version
-	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO post_revisions (post_id, version, title, content, created_at)
+			SELECT id, version, title, content, updated_at FROM posts
+			WHERE id = $1 AND version = $2
+			FOR UPDATE`,
+			post.ID,
+			post.Version,
+		)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return ErrNotFound
+			}
 
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		post.Content,
-		post.Title,
-		post.ID,
-		post.Version,
-	).Scan(&post.Version)
-	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return ErrNotFound
-		default:
 			return err
 		}
-	}
 
-	return nil
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		query := `UPDATE posts 
+		SET content = $1, title = $2, version = version + 1, updated_at = NOW()
+		WHERE id = $3 AND version = $4
+		RETURNING version, updated_at
+		`
+
+		err = tx.QueryRowContext(
+			ctx,
+			query,
+			post.Content,
+			post.Title,
+			post.ID,
+			post.Version,
+		).Scan(&post.Version, &post.UpdatedAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		return nil
+	})
 }
 
+// GetUserFeed returns the user's posts and those of the users they follow.
+// Follows of private accounts only exist once approved, so their posts are
+// only included for approved followers. Posts of deactivated accounts and
+// reposts of posts the user cannot see are left out.
 func (s *PostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
-	orderBy := "DESC"
-	if fq.Sort == "asc" {
-		orderBy = "ASC"
-	}
+	query := feedSelect("$1") + `
+		LEFT JOIN followers f ON f.user_id = $1 AND f.follower_id = p.user_id
+		WHERE (p.user_id = $1 OR f.user_id IS NOT NULL)
+			AND ` + authorShown("u") + `
+			AND NOT EXISTS (
+				SELECT 1 FROM blocks b
+				WHERE (b.blocker_id = p.user_id AND b.blocked_id = $1) OR (b.blocker_id = $1 AND b.blocked_id = p.user_id)
+			)
+			AND NOT EXISTS (SELECT 1 FROM mutes m WHERE m.muter_id = $1 AND m.muted_id = p.user_id)
+			AND (p.repost_of_id IS NULL OR ` + originalPostVisible("$1") + `)
+	`
 
-	query := `
+	return s.list(ctx, query, fq, userID)
+}
+
+// GetUserPosts returns the posts of a single author, filtered and paged the
+// same way as GetUserFeed. Callers check that the viewer is allowed to see
+// them with UserStore.CanViewPosts.
+func (s *PostStore) GetUserPosts(ctx context.Context, authorID, viewerID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	query := feedSelect("$2") + `
+		WHERE p.user_id = $1
+			AND (p.repost_of_id IS NULL OR ` + originalPostVisible("$2") + `)
+	`
+
+	return s.list(ctx, query, fq, authorID, viewerID)
+}
+
+// feedSelect selects the posts scanned by list for viewer, a query
+// parameter.
+func feedSelect(viewer string) string {
+	return `
 		SELECT 
 			p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags,
 			u.username, 
-			COUNT(c.id) as comments_count
+			COUNT(c.id) as comments_count,
+			p.reaction_counts, pr.emoji,
+			p.repost_of_id, p.quote_of_id, p.is_quote,
+			` + originalPostColumns(originalPostVisible(viewer)) + `
 		FROM posts p
 		LEFT JOIN comments c ON c.post_id = p.id
 		LEFT JOIN users u ON p.user_id = u.id
-		LEFT JOIN followers f ON f.user_id = $1 AND f.follower_id = p.user_id
-		WHERE (p.user_id = $1 OR f.user_id IS NOT NULL)
-	`
+		LEFT JOIN post_reactions pr ON pr.post_id = p.id AND pr.user_id = ` + viewer +
+		originalPostJoins
+}
+
+// list appends the filters, ordering and paging of fq to query, which takes
+// args as its first parameters.
+func (s *PostStore) list(ctx context.Context, query string, fq PaginatedFeedQuery, args ...interface{}) ([]PostWithMetadata, error) {
+	orderBy := "DESC"
+	if fq.Sort == "asc" {
+		orderBy = "ASC"
+	}
 
-	args := []interface{}{userID}
-	argPosition := 2
+	argPosition := len(args) + 1
 
 	if fq.Search != "" {
 		query += ` AND (p.title ILIKE $` + strconv.Itoa(argPosition) +
@@ -188,7 +311,7 @@ func (s *PostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedF
 	}
 
 	query += `
-		GROUP BY p.id, u.username
+		GROUP BY p.id, u.username, pr.emoji, o.id, ou.id
 		ORDER BY p.created_at ` + orderBy + `
 		LIMIT $` + strconv.Itoa(argPosition) + ` OFFSET $` + strconv.Itoa(argPosition+1) + `
 	`
@@ -208,7 +331,8 @@ func (s *PostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedF
 
 	for rows.Next() {
 		var post PostWithMetadata
-		if err := rows.Scan(
+		var original originalPostRow
+		if err := rows.Scan(append([]any{
 			&post.ID,
 			&post.UserID,
 			&post.Title,
@@ -218,9 +342,15 @@ func (s *PostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedF
 			pq.Array(&post.Tags),
 			&post.User.Username,
 			&post.CommentsCount,
-		); err != nil {
+			&post.ReactionCounts,
+			&post.MyReaction,
+			&post.RepostOfID,
+			&post.QuoteOfID,
+			&post.IsQuote,
+		}, original.dest()...)...); err != nil {
 			return nil, err
 		}
+		post.Original = original.original(&post.Post)
 		feed = append(feed, post)
 	}
 
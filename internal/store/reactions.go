@@ -0,0 +1,202 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ReactionTarget is the kind of content a reaction is left on.
+type ReactionTarget int
+
+const (
+	PostReaction ReactionTarget = iota
+	CommentReaction
+)
+
+type reactionTable struct {
+	// reactions is the table of the reactions, column its reference to the
+	// target and target the table holding the reaction_counts column
+	reactions, column, target string
+}
+
+var reactionTables = map[ReactionTarget]reactionTable{
+	PostReaction:    {reactions: "post_reactions", column: "post_id", target: "posts"},
+	CommentReaction: {reactions: "comment_reactions", column: "comment_id", target: "comments"},
+}
+
+// ReactionCounts maps each emoji to the number of users who reacted with it.
+// It is kept up to date on the target row so listings don't need to
+// aggregate the reactions.
+type ReactionCounts map[string]int
+
+func (c *ReactionCounts) Scan(src any) error {
+	*c = ReactionCounts{}
+
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	default:
+		return fmt.Errorf("cannot scan %T into ReactionCounts", src)
+	}
+}
+
+type ReactionStore struct {
+	db *sql.DB
+}
+
+// React sets the reaction of userID on the target, replacing a previous one.
+// It returns ErrBlocked if the author of the target and the user have
+// blocked one another.
+func (s *ReactionStore) React(ctx context.Context, target ReactionTarget, targetID, userID int64, emoji string) error {
+	t := reactionTables[target]
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 1 FROM blocks b
+			WHERE (b.blocker_id = t.user_id AND b.blocked_id = $2) OR (b.blocker_id = $2 AND b.blocked_id = t.user_id)
+		)
+		FROM %s t
+		WHERE t.id = $1
+		`, t.target)
+
+		var blocked bool
+		if err := tx.QueryRowContext(ctx, query, targetID, userID).Scan(&blocked); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if blocked {
+			return ErrBlocked
+		}
+
+		// the previous reaction is locked so concurrent changes of it wait
+		// and count from the one before them
+		query = fmt.Sprintf(`
+		SELECT emoji FROM %s WHERE %s = $1 AND user_id = $2 FOR UPDATE
+		`, t.reactions, t.column)
+
+		var previous string
+		err := tx.QueryRowContext(ctx, query, targetID, userID).Scan(&previous)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+		case err != nil:
+			return err
+		case previous == emoji:
+			return nil
+		}
+
+		query = fmt.Sprintf(`
+		INSERT INTO %[1]s AS r (%[2]s, user_id, emoji) VALUES ($1, $2, $3)
+		ON CONFLICT (%[2]s, user_id) DO UPDATE SET emoji = EXCLUDED.emoji, created_at = NOW()
+		WHERE r.emoji <> EXCLUDED.emoji
+		RETURNING r.xmax = 0
+		`, t.reactions, t.column)
+
+		var inserted bool
+		err = tx.QueryRowContext(ctx, query, targetID, userID, emoji).Scan(&inserted)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			// a concurrent first reaction with the same emoji
+			return nil
+		case err != nil:
+			return err
+		case inserted:
+		case previous == "":
+			// a concurrent first reaction was replaced, its emoji is unknown
+			return ErrConflict
+		default:
+			if err := updateReactionCounts(ctx, tx, t, targetID, previous, -1); err != nil {
+				return err
+			}
+		}
+
+		return updateReactionCounts(ctx, tx, t, targetID, emoji, 1)
+	})
+}
+
+// Unreact removes the reaction of userID from the target.
+func (s *ReactionStore) Unreact(ctx context.Context, target ReactionTarget, targetID, userID int64) error {
+	t := reactionTables[target]
+
+	query := fmt.Sprintf(`
+	DELETE FROM %s WHERE %s = $1 AND user_id = $2
+	RETURNING emoji
+	`, t.reactions, t.column)
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var emoji string
+		if err := tx.QueryRowContext(ctx, query, targetID, userID).Scan(&emoji); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		return updateReactionCounts(ctx, tx, t, targetID, emoji, -1)
+	})
+}
+
+// Get returns the emoji userID reacted to the target with, or ErrNotFound.
+func (s *ReactionStore) Get(ctx context.Context, target ReactionTarget, targetID, userID int64) (string, error) {
+	t := reactionTables[target]
+
+	query := fmt.Sprintf(`
+	SELECT emoji FROM %s WHERE %s = $1 AND user_id = $2
+	`, t.reactions, t.column)
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var emoji string
+	if err := s.db.QueryRowContext(ctx, query, targetID, userID).Scan(&emoji); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return "", ErrNotFound
+		default:
+			return "", err
+		}
+	}
+
+	return emoji, nil
+}
+
+func updateReactionCounts(ctx context.Context, tx *sql.Tx, t reactionTable, targetID int64, emoji string, delta int) error {
+	query := fmt.Sprintf(`
+	UPDATE %s SET reaction_counts = %s
+	WHERE id = $1
+	`, t.target, reactionCountsDelta("$2::text", "$3::int"))
+
+	_, err := tx.ExecContext(ctx, query, targetID, emoji, delta)
+	return err
+}
+
+// reactionCountsDelta is the SQL expression adding delta to the count of
+// emoji in reaction_counts. Emojis nobody reacts with anymore are removed.
+func reactionCountsDelta(emoji, delta string) string {
+	count := fmt.Sprintf("COALESCE((reaction_counts->>%s)::int, 0) + %s", emoji, delta)
+
+	return fmt.Sprintf(`CASE
+		WHEN %[1]s > 0 THEN jsonb_set(reaction_counts, ARRAY[%[2]s], to_jsonb(%[1]s))
+		ELSE reaction_counts - %[2]s
+	END`, count, emoji)
+}
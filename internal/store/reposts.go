@@ -0,0 +1,193 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrPrivatePost   = errors.New("posts of private accounts cannot be shared")
+	ErrQuoteNotFound = errors.New("quoted post not found")
+)
+
+// OriginalPost is the post shared by a repost or a quote post. A quote post
+// whose original was deleted, or is hidden from the viewer, carries a
+// tombstone with only Unavailable set.
+type OriginalPost struct {
+	ID          int64  `json:"id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Content     string `json:"content,omitempty"`
+	UserID      int64  `json:"user_id,omitempty"`
+	Username    string `json:"username,omitempty"`
+	CreatedAt   string `json:"created_at,omitempty"`
+	Unavailable bool   `json:"unavailable"`
+}
+
+// originalPostRow scans the columns selected by originalPostColumns.
+type originalPostRow struct {
+	id        *int64
+	title     *string
+	content   *string
+	userID    *int64
+	username  *string
+	createdAt *string
+	visible   bool
+}
+
+// originalPostColumns selects the original of post p, joined as o with its
+// author ou, and whether visible, an SQL boolean, allows showing it.
+func originalPostColumns(visible string) string {
+	return `o.id, o.title, o.content, o.user_id, ou.username, o.created_at, ` + visible
+}
+
+// originalPostJoins joins the original of post p and its author.
+const originalPostJoins = `
+	LEFT JOIN posts o ON o.id = COALESCE(p.repost_of_id, p.quote_of_id)
+	LEFT JOIN users ou ON ou.id = o.user_id`
+
+// originalPostVisible is the SQL condition for viewer, a query parameter,
+// to see the original joined by originalPostJoins.
+func originalPostVisible(viewer string) string {
+	return fmt.Sprintf(`COALESCE(`+authorShown("ou")+`
+		AND NOT EXISTS (
+			SELECT 1 FROM blocks ob
+			WHERE (ob.blocker_id = ou.id AND ob.blocked_id = %[1]s) OR (ob.blocker_id = %[1]s AND ob.blocked_id = ou.id)
+		)
+		AND (NOT ou.is_private OR ou.id = %[1]s
+			OR EXISTS (SELECT 1 FROM followers vf WHERE vf.user_id = %[1]s AND vf.follower_id = ou.id)), false)`, viewer)
+}
+
+func (r *originalPostRow) dest() []any {
+	return []any{&r.id, &r.title, &r.content, &r.userID, &r.username, &r.createdAt, &r.visible}
+}
+
+// original returns the OriginalPost of post, nil when it shares nothing.
+func (r *originalPostRow) original(post *Post) *OriginalPost {
+	if post.RepostOfID == nil && !post.IsQuote {
+		return nil
+	}
+
+	if r.id == nil || !r.visible {
+		return &OriginalPost{Unavailable: true}
+	}
+
+	return &OriginalPost{
+		ID:        *r.id,
+		Title:     *r.title,
+		Content:   *r.content,
+		UserID:    *r.userID,
+		Username:  *r.username,
+		CreatedAt: *r.createdAt,
+	}
+}
+
+// Repost shares the post post.RepostOfID as post.UserID. Reposting a repost
+// shares its original. It returns ErrConflict if the user already reposted
+// it, and ErrBlocked or ErrPrivatePost if they cannot share it.
+func (s *PostStore) Repost(ctx context.Context, post *Post) error {
+	query := `
+	INSERT INTO posts (title, content, user_id, tags, repost_of_id)
+	VALUES ('', '', $1, '{}', $2)
+	RETURNING id, created_at, updated_at, reaction_counts
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		originalID, err := shareableOriginal(ctx, tx, *post.RepostOfID, post.UserID)
+		if err != nil {
+			return err
+		}
+
+		post.RepostOfID = &originalID
+
+		err = tx.QueryRowContext(ctx, query, post.UserID, originalID).Scan(
+			&post.ID,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+			&post.ReactionCounts,
+		)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return ErrConflict
+			}
+
+			return err
+		}
+
+		return nil
+	})
+}
+
+// Unrepost removes the repost of postID, or of its original, by userID.
+func (s *PostStore) Unrepost(ctx context.Context, userID, postID int64) error {
+	query := `
+	DELETE FROM posts
+	WHERE user_id = $1
+		AND repost_of_id = (SELECT COALESCE(repost_of_id, id) FROM posts WHERE id = $2)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, userID, postID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// shareableOriginal returns the ID of the original of postID, following a
+// repost to the post it shares, after checking userID may share it.
+func shareableOriginal(ctx context.Context, tx *sql.Tx, postID, userID int64) (int64, error) {
+	query := `
+	SELECT o.id, u.is_private AND u.id <> $2,
+		EXISTS (
+			SELECT 1 FROM blocks b
+			WHERE (b.blocker_id = u.id AND b.blocked_id = $2) OR (b.blocker_id = $2 AND b.blocked_id = u.id)
+		)
+	FROM posts p
+	JOIN posts o ON o.id = COALESCE(p.repost_of_id, p.id)
+	JOIN users u ON u.id = o.user_id AND ` + authorShown("u") + `
+	WHERE p.id = $1
+	`
+
+	var (
+		originalID       int64
+		private, blocked bool
+	)
+
+	err := tx.QueryRowContext(ctx, query, postID, userID).Scan(&originalID, &private, &blocked)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	switch {
+	case blocked:
+		return 0, ErrBlocked
+	case private:
+		return 0, ErrPrivatePost
+	}
+
+	return originalID, nil
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// PostRevision is a version of a post replaced by an edit. CreatedAt is when
+// that version was written.
+type PostRevision struct {
+	PostID    int64  `json:"post_id"`
+	Version   int    `json:"version"`
+	Title     string `json:"title"`
+	Content   string `json:"content"`
+	CreatedAt string `json:"created_at"`
+}
+
+// GetRevisions returns the earlier versions of a post, most recent first.
+func (s *PostStore) GetRevisions(ctx context.Context, postID int64) ([]PostRevision, error) {
+	query := `
+	SELECT post_id, version, title, content, created_at
+	FROM post_revisions
+	WHERE post_id = $1
+	ORDER BY version DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []PostRevision{}
+	for rows.Next() {
+		var rev PostRevision
+		if err := rows.Scan(&rev.PostID, &rev.Version, &rev.Title, &rev.Content, &rev.CreatedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// GetRevision returns the given earlier version of a post, or ErrNotFound.
+func (s *PostStore) GetRevision(ctx context.Context, postID int64, version int) (*PostRevision, error) {
+	query := `
+	SELECT post_id, version, title, content, created_at
+	FROM post_revisions
+	WHERE post_id = $1 AND version = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var rev PostRevision
+	err := s.db.QueryRowContext(ctx, query, postID, version).Scan(
+		&rev.PostID,
+		&rev.Version,
+		&rev.Title,
+		&rev.Content,
+		&rev.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &rev, nil
+}
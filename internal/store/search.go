@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"strings"
+)
+
+type UserSearchResult struct {
+	ID            int64   `json:"id"`
+	Username      string  `json:"username"`
+	DisplayName   string  `json:"display_name"`
+	AvatarID      *int64  `json:"avatar_id"`
+	FollowerCount int64   `json:"follower_count"`
+	FollowedByMe  bool    `json:"followed_by_me"`
+	Rank          float64 `json:"-"`
+}
+
+const (
+	// followedBoost ranks users the viewer follows above close matches
+	followedBoost = 0.5
+	// networkBoost ranks users followed by someone the viewer follows
+	networkBoost = 0.25
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Search finds active users whose username or display name is similar to the
+// query, or whose username starts with it, ranked by trigram similarity plus
+// a boost for users in the viewer's network. Users who blocked the viewer are
+// left out. It returns the cursor of the next page, empty on the last page.
+func (s *UserStore) Search(ctx context.Context, viewerID int64, sq UserSearchQuery) ([]UserSearchResult, string, error) {
+	query := `
+	SELECT id, username, display_name, avatar_media_id, follower_count, followed_by_me, rank
+	FROM (
+		SELECT u.id, u.username, u.display_name, u.avatar_media_id, u.follower_count,
+			f.user_id IS NOT NULL AS followed_by_me,
+			GREATEST(similarity(u.username, $1), similarity(u.display_name, $1))::float8
+				+ CASE
+					WHEN f.user_id IS NOT NULL THEN $4::float8
+					WHEN EXISTS (
+						SELECT 1 FROM followers mine
+						JOIN followers theirs ON theirs.user_id = mine.follower_id
+						WHERE mine.user_id = $2 AND theirs.follower_id = u.id
+					) THEN $5::float8
+					ELSE 0
+				END AS rank
+		FROM users u
+		LEFT JOIN followers f ON f.user_id = $2 AND f.follower_id = u.id
+		WHERE u.is_active = true
+			AND (u.username % $1 OR u.display_name % $1 OR u.username ILIKE $3)
+			AND NOT EXISTS (SELECT 1 FROM blocks b WHERE b.blocker_id = u.id AND b.blocked_id = $2)
+	) results
+	WHERE ($6::float8 IS NULL OR (rank, id) < ($6, $7))
+	ORDER BY rank DESC, id DESC
+	LIMIT $8
+	`
+
+	var afterRank *float64
+	var afterID int64
+	if sq.After != nil {
+		afterRank, afterID = &sq.After.Rank, sq.After.ID
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	// one extra row tells whether there is a next page
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		sq.Query,
+		viewerID,
+		likeEscaper.Replace(sq.Query)+"%",
+		followedBoost,
+		networkBoost,
+		afterRank,
+		afterID,
+		sq.Limit+1,
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	users := []UserSearchResult{}
+	for rows.Next() {
+		var u UserSearchResult
+		if err := rows.Scan(
+			&u.ID,
+			&u.Username,
+			&u.DisplayName,
+			&u.AvatarID,
+			&u.FollowerCount,
+			&u.FollowedByMe,
+			&u.Rank,
+		); err != nil {
+			return nil, "", err
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(users) > sq.Limit {
+		users = users[:sq.Limit]
+		last := users[len(users)-1]
+		next = RankCursor{Rank: last.Rank, ID: last.ID}.Encode()
+	}
+
+	return users, next, nil
+}
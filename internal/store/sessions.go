@@ -0,0 +1,324 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var ErrTokenReused = errors.New("refresh token reused")
+
+type Session struct {
+	ID         string  `json:"id"`
+	UserID     int64   `json:"user_id"`
+	UserAgent  string  `json:"user_agent"`
+	IP         string  `json:"ip"`
+	CreatedAt  string  `json:"created_at"`
+	LastSeenAt string  `json:"last_seen_at"`
+	RevokedAt  *string `json:"revoked_at"`
+}
+
+type SessionStore struct {
+	db *sql.DB
+}
+
+// Create starts a new session (token family) for the user and stores its
+// first refresh token.
+func (s *SessionStore) Create(ctx context.Context, session *Session, token string, exp time.Duration) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+		INSERT INTO sessions (id, user_id, user_agent, ip)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at, last_seen_at
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			session.ID,
+			session.UserID,
+			session.UserAgent,
+			session.IP,
+		).Scan(
+			&session.CreatedAt,
+			&session.LastSeenAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		return s.createRefreshToken(ctx, tx, session.ID, token, exp)
+	})
+}
+
+func (s *SessionStore) GetByID(ctx context.Context, id string) (*Session, error) {
+	query := `
+	SELECT id, user_id, user_agent, ip, created_at, last_seen_at, revoked_at
+	FROM sessions WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	session := &Session{}
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&session.ID,
+		&session.UserID,
+		&session.UserAgent,
+		&session.IP,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.RevokedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return session, nil
+}
+
+// GetByRefreshToken returns the session the refresh token belongs to, whether
+// or not the token was already used.
+func (s *SessionStore) GetByRefreshToken(ctx context.Context, token string) (*Session, error) {
+	query := `
+	SELECT s.id, s.user_id, s.user_agent, s.ip, s.created_at, s.last_seen_at, s.revoked_at
+	FROM sessions s
+	JOIN refresh_tokens rt ON rt.session_id = s.id
+	WHERE rt.token = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	session := &Session{}
+	err := s.db.QueryRowContext(ctx, query, hashToken(token)).Scan(
+		&session.ID,
+		&session.UserID,
+		&session.UserAgent,
+		&session.IP,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.RevokedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return session, nil
+}
+
+// Rotate exchanges a refresh token for a new one in the same session. If the
+// presented token was already used the whole session is revoked and returned
+// along with ErrTokenReused.
+func (s *SessionStore) Rotate(ctx context.Context, token, newToken string, exp time.Duration) (*Session, error) {
+	var (
+		session *Session
+		reused  bool
+	)
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+		SELECT s.id, s.user_id, s.user_agent, s.ip, s.created_at, s.last_seen_at, s.revoked_at, rt.used_at IS NOT NULL
+		FROM refresh_tokens rt
+		JOIN sessions s ON s.id = rt.session_id
+		WHERE rt.token = $1 AND rt.expiry > $2
+		FOR UPDATE
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		session = &Session{}
+		var used bool
+		err := tx.QueryRowContext(ctx, query, hashToken(token), time.Now()).Scan(
+			&session.ID,
+			&session.UserID,
+			&session.UserAgent,
+			&session.IP,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+			&session.RevokedAt,
+			&used,
+		)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if session.RevokedAt != nil {
+			return ErrNotFound
+		}
+
+		if used {
+			reused = true
+			return s.revoke(ctx, tx, session.ID)
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET used_at = NOW() WHERE token = $1`, hashToken(token)); err != nil {
+			return err
+		}
+
+		return s.createRefreshToken(ctx, tx, session.ID, newToken, exp)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if reused {
+		return session, ErrTokenReused
+	}
+
+	return session, nil
+}
+
+// GetByUserID lists the user's sessions that can still be refreshed, most
+// recently used first.
+func (s *SessionStore) GetByUserID(ctx context.Context, userID int64) ([]Session, error) {
+	query := `
+	SELECT s.id, s.user_id, s.user_agent, s.ip, s.created_at, s.last_seen_at, s.revoked_at
+	FROM sessions s
+	WHERE s.user_id = $1 AND s.revoked_at IS NULL AND EXISTS (
+		SELECT 1 FROM refresh_tokens rt
+		WHERE rt.session_id = s.id AND rt.used_at IS NULL AND rt.expiry > $2
+	)
+	ORDER BY s.last_seen_at DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var session Session
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.UserAgent,
+			&session.IP,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+			&session.RevokedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// Touch records activity on the session from the given IP. It returns
+// ErrNotFound if the session was revoked.
+func (s *SessionStore) Touch(ctx context.Context, session *Session, ip string) error {
+	query := `UPDATE sessions SET last_seen_at = NOW(), ip = $2 WHERE id = $1 AND revoked_at IS NULL RETURNING last_seen_at`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, session.ID, ip).Scan(&session.LastSeenAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	session.IP = ip
+
+	return nil
+}
+
+// RevokeForUser revokes one of the user's active sessions.
+func (s *SessionStore) RevokeForUser(ctx context.Context, id string, userID int64) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *SessionStore) Revoke(ctx context.Context, id string) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return s.revoke(ctx, tx, id)
+	})
+}
+
+func (s *SessionStore) RevokeAllForUser(ctx context.Context, userID int64) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID)
+	return err
+}
+
+func (s *SessionStore) revoke(ctx context.Context, tx *sql.Tx, id string) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, id)
+	return err
+}
+
+func (s *SessionStore) createRefreshToken(ctx context.Context, tx *sql.Tx, sessionID, token string, exp time.Duration) error {
+	query := `INSERT INTO refresh_tokens (token, session_id, expiry) VALUES ($1, $2, $3)`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, hashToken(token), sessionID, time.Now().Add(exp))
+	return err
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
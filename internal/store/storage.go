@@ -22,6 +22,11 @@ type Storage struct {
 		Delete(context.Context, int64) error
 		Update(context.Context, *Post) error
 		GetUserFeed(context.Context, int64, PaginatedFeedQuery) ([]PostWithMetadata, error)
+		GetUserPosts(ctx context.Context, authorID, viewerID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error)
+		Repost(context.Context, *Post) error
+		Unrepost(ctx context.Context, userID, postID int64) error
+		GetRevisions(ctx context.Context, postID int64) ([]PostRevision, error)
+		GetRevision(ctx context.Context, postID int64, version int) (*PostRevision, error)
 	}
 	Users interface {
 		GetByID(context.Context, int64) (*User, error)
@@ -30,27 +35,120 @@ type Storage struct {
 		CreateAndInvite(ctx context.Context, user *User, token string, exp time.Duration) error
 		Activate(ctx context.Context, token string) error
 		Delete(ctx context.Context, id int64) error
+		CreatePasswordReset(ctx context.Context, userID int64, token string, exp time.Duration) error
+		ResetPassword(ctx context.Context, token, newPassword string) (int64, error)
+		ReissueInvitation(ctx context.Context, email, token string, exp time.Duration) (*User, error)
+		DeleteUnactivated(ctx context.Context, before time.Time) (int64, error)
+		CreateEmailChange(ctx context.Context, userID int64, newEmail, token string, exp time.Duration) error
+		ConfirmEmailChange(ctx context.Context, token string) (*User, string, error)
+		CreateWithIdentity(ctx context.Context, user *User, identity *Identity) error
+		UpdateProfile(ctx context.Context, user *User) error
+		CanViewPosts(ctx context.Context, authorID, viewerID int64) (bool, error)
+		Search(ctx context.Context, viewerID int64, sq UserSearchQuery) ([]UserSearchResult, string, error)
+		GetDeactivatedByEmail(ctx context.Context, email string) (*User, error)
+		GetDeactivatedByID(ctx context.Context, id int64) (*User, error)
+		Deactivate(ctx context.Context, userID int64, deleteAfter time.Time) error
+		Reactivate(ctx context.Context, userID int64) error
+		GetDueForDeletion(ctx context.Context, now time.Time, limit int) ([]int64, error)
+		Purge(ctx context.Context, userID int64, anonymize bool) ([]Media, error)
 	}
 	Comments interface {
-		GetByPostID(context.Context, int64) ([]Comment, error)
+		GetByID(context.Context, int64) (*Comment, error)
+		GetByPostID(ctx context.Context, postID, viewerID int64, cq CursorQuery) ([]Comment, string, error)
+		GetReplies(ctx context.Context, commentID, viewerID int64, cq CursorQuery) ([]Comment, string, error)
 		Create(context.Context, *Comment) error
+		Update(context.Context, *Comment) error
+		Delete(context.Context, int64) error
 	}
 	Followers interface {
-		Follow(ctx context.Context, followerID int64, userID int64) error
+		Follow(ctx context.Context, followerID int64, userID int64) (bool, error)
 		Unfollow(ctx context.Context, followerID int64, userID int64) error
+		IsFollowing(ctx context.Context, followerID int64, userID int64) (bool, error)
+		GetFollowers(ctx context.Context, userID, viewerID int64, cq CursorQuery) ([]FollowUser, string, error)
+		GetFollowing(ctx context.Context, userID, viewerID int64, cq CursorQuery) ([]FollowUser, string, error)
+		GetFollowRequests(ctx context.Context, userID int64, cq CursorQuery) ([]FollowUser, string, error)
+		ApproveFollowRequest(ctx context.Context, userID, requesterID int64) error
+		RejectFollowRequest(ctx context.Context, userID, requesterID int64) error
+		GetSuggestions(ctx context.Context, userID int64, limit int) ([]Suggestion, error)
+	}
+	Blocks interface {
+		Block(ctx context.Context, blockerID, userID int64) error
+		Unblock(ctx context.Context, blockerID, userID int64) error
+	}
+	Mutes interface {
+		Mute(ctx context.Context, muterID, userID int64) error
+		Unmute(ctx context.Context, muterID, userID int64) error
+	}
+	Reactions interface {
+		React(ctx context.Context, target ReactionTarget, targetID, userID int64, emoji string) error
+		Unreact(ctx context.Context, target ReactionTarget, targetID, userID int64) error
+		Get(ctx context.Context, target ReactionTarget, targetID, userID int64) (string, error)
 	}
 	Roles interface {
 		GetByName(ctx context.Context, name string) (*Role, error)
 	}
+	Sessions interface {
+		Create(ctx context.Context, session *Session, token string, exp time.Duration) error
+		GetByID(ctx context.Context, id string) (*Session, error)
+		GetByRefreshToken(ctx context.Context, token string) (*Session, error)
+		Rotate(ctx context.Context, token, newToken string, exp time.Duration) (*Session, error)
+		Revoke(ctx context.Context, id string) error
+		RevokeAllForUser(ctx context.Context, userID int64) error
+		GetByUserID(ctx context.Context, userID int64) ([]Session, error)
+		Touch(ctx context.Context, session *Session, ip string) error
+		RevokeForUser(ctx context.Context, id string, userID int64) error
+	}
+	MFA interface {
+		SetTOTPSecret(ctx context.Context, userID int64, secret string) error
+		EnableTOTP(ctx context.Context, userID int64, recoveryCodes []string) error
+		DisableTOTP(ctx context.Context, userID int64) error
+		UseTOTPStep(ctx context.Context, userID int64, step int64) error
+		UseRecoveryCode(ctx context.Context, userID int64, code string) error
+	}
+	APIKeys interface {
+		Create(ctx context.Context, key *APIKey, token string, expiry *time.Time) error
+		GetByUserID(ctx context.Context, userID int64) ([]APIKey, error)
+		GetByToken(ctx context.Context, token string) (*APIKey, error)
+		Touch(ctx context.Context, key *APIKey) error
+		Delete(ctx context.Context, id, userID int64) error
+	}
+	Media interface {
+		Create(ctx context.Context, media *Media, quota int64) error
+		GetByID(ctx context.Context, id int64) (*Media, error)
+		GetUsage(ctx context.Context, userID int64) (int64, error)
+		Delete(ctx context.Context, id, userID int64) (*Media, error)
+	}
+	Exports interface {
+		Create(ctx context.Context, export *DataExport, pendingSince time.Time) error
+		Complete(ctx context.Context, export *DataExport, token string, exp time.Duration) error
+		GetByToken(ctx context.Context, token string) (*DataExport, error)
+		Delete(ctx context.Context, id int64) error
+		DeleteExpired(ctx context.Context, now time.Time) ([]string, error)
+		GetPosts(ctx context.Context, userID int64) ([]Post, error)
+		GetComments(ctx context.Context, userID int64) ([]Comment, error)
+	}
+	Identities interface {
+		GetUserID(ctx context.Context, provider, subject string) (int64, error)
+		Create(ctx context.Context, identity *Identity) error
+	}
 }
 
 func NewStorage(db *sql.DB) Storage {
 	return Storage{
-		Posts:     &PostStore{db},
-		Users:     &UserStore{db},
-		Comments:  &CommentStore{db},
-		Followers: &FollowerStore{db},
-		Roles:     &RoleStore{db},
+		Posts:      &PostStore{db},
+		Users:      &UserStore{db},
+		Comments:   &CommentStore{db},
+		Followers:  &FollowerStore{db},
+		Blocks:     &BlockStore{db},
+		Mutes:      &MuteStore{db},
+		Reactions:  &ReactionStore{db},
+		Roles:      &RoleStore{db},
+		Sessions:   &SessionStore{db},
+		MFA:        &MFAStore{db},
+		APIKeys:    &APIKeyStore{db},
+		Identities: &IdentityStore{db},
+		Media:      &MediaStore{db},
+		Exports:    &ExportStore{db},
 	}
 }
 
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
+// Suggestion is an account the user may want to follow.
+type Suggestion struct {
+	ID            int64  `json:"id"`
+	Username      string `json:"username"`
+	DisplayName   string `json:"display_name"`
+	AvatarID      *int64 `json:"avatar_id"`
+	FollowerCount int64  `json:"follower_count"`
+	// MutualCount is the number of users followed by the user who follow
+	// this account
+	MutualCount int64 `json:"mutual_count"`
+	// SharedTags are the user's most used tags this account posts with
+	SharedTags []string `json:"shared_tags"`
+}
+
+// suggestionTags is the number of the user's most used tags matched against
+// other users' posts.
+const suggestionTags = 5
+
+// GetSuggestions returns up to limit accounts followed by the users userID
+// follows and accounts posting with userID's most used tags, best first.
+// Accounts already followed or requested, blocked in either direction, muted
+// or inactive are left out.
+func (s *FollowerStore) GetSuggestions(ctx context.Context, userID int64, limit int) ([]Suggestion, error) {
+	query := `
+	WITH my_tags AS (
+		SELECT tag FROM posts, unnest(tags) AS tag
+		WHERE user_id = $1
+		GROUP BY tag
+		ORDER BY COUNT(*) DESC, tag
+		LIMIT $2
+	),
+	mutuals AS (
+		SELECT theirs.follower_id AS id, COUNT(*) AS mutual_count
+		FROM followers mine
+		JOIN followers theirs ON theirs.user_id = mine.follower_id
+		WHERE mine.user_id = $1
+		GROUP BY theirs.follower_id
+	),
+	tagged AS (
+		SELECT p.user_id AS id, array_agg(DISTINCT t.tag) AS shared_tags
+		FROM posts p, unnest(p.tags) AS t(tag)
+		WHERE p.tags && (SELECT COALESCE(array_agg(tag), '{}') FROM my_tags)
+			AND t.tag IN (SELECT tag FROM my_tags)
+		GROUP BY p.user_id
+	)
+	SELECT u.id, u.username, u.display_name, u.avatar_media_id, u.follower_count,
+		COALESCE(m.mutual_count, 0), COALESCE(t.shared_tags, '{}')
+	FROM users u
+	LEFT JOIN mutuals m ON m.id = u.id
+	LEFT JOIN tagged t ON t.id = u.id
+	WHERE (m.id IS NOT NULL OR t.id IS NOT NULL)
+		AND u.id <> $1
+		AND u.is_active = true
+		AND NOT EXISTS (SELECT 1 FROM followers f WHERE f.user_id = $1 AND f.follower_id = u.id)
+		AND NOT EXISTS (SELECT 1 FROM follow_requests fr WHERE fr.requester_id = $1 AND fr.user_id = u.id)
+		AND NOT EXISTS (
+			SELECT 1 FROM blocks b
+			WHERE (b.blocker_id = $1 AND b.blocked_id = u.id) OR (b.blocker_id = u.id AND b.blocked_id = $1)
+		)
+		AND NOT EXISTS (SELECT 1 FROM mutes mu WHERE mu.muter_id = $1 AND mu.muted_id = u.id)
+	ORDER BY 2 * COALESCE(m.mutual_count, 0) + COALESCE(cardinality(t.shared_tags), 0) DESC,
+		u.follower_count DESC, u.id
+	LIMIT $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, suggestionTags, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []Suggestion{}
+	for rows.Next() {
+		var sg Suggestion
+		if err := rows.Scan(
+			&sg.ID,
+			&sg.Username,
+			&sg.DisplayName,
+			&sg.AvatarID,
+			&sg.FollowerCount,
+			&sg.MutualCount,
+			pq.Array(&sg.SharedTags),
+		); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, sg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
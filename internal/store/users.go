@@ -6,20 +6,46 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
 type User struct {
-	ID        int64    `json:"id"`
-	Username  string   `json:"username"`
-	Email     string   `json:"email"`
-	Password  password `json:"-"`
-	CreatedAt string   `json:"created_at"`
-	IsActive  bool     `json:"is_active"`
-	RoleID    int64    `json:"role_id"`
-	Role      Role     `json:"role"`
+	ID          int64    `json:"id"`
+	Username    string   `json:"username"`
+	Email       string   `json:"email"`
+	Password    password `json:"-"`
+	CreatedAt   string   `json:"created_at"`
+	IsActive    bool     `json:"is_active"`
+	RoleID      int64    `json:"role_id"`
+	Role        Role     `json:"role"`
+	TOTPEnabled bool     `json:"-"`
+	TOTPSecret  string   `json:"-"`
+	Profile
+	FollowerCount  int64 `json:"follower_count"`
+	FollowingCount int64 `json:"following_count"`
+	// DeactivatedAt is set while the account awaits deletion
+	DeactivatedAt *string `json:"-"`
+}
+
+const (
+	BirthdayPublic  = "public"
+	BirthdayPrivate = "private"
+)
+
+type Profile struct {
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+	Website     string `json:"website"`
+	Location    string `json:"location"`
+	// Birthday is formatted as YYYY-MM-DD
+	Birthday           *string `json:"birthday"`
+	BirthdayVisibility string  `json:"birthday_visibility"`
+	AvatarID           *int64  `json:"avatar_id"`
+	// IsPrivate limits the user's posts to approved followers
+	IsPrivate bool `json:"is_private"`
 }
 
 type password struct {
@@ -87,7 +113,10 @@ func (s *UserStore) Create(ctx context.Context, tx *sql.Tx, user *User) error {
 
 func (s *UserStore) GetByID(ctx context.Context, id int64) (*User, error) {
 	query := `
-	SELECT users.id, username, email, password, created_at, roles.id, roles.name, roles.description, roles.level 
+	SELECT users.id, username, email, password, created_at, totp_enabled, COALESCE(totp_secret, ''),
+		display_name, bio, website, location, to_char(birthday, 'YYYY-MM-DD'), birthday_visibility, avatar_media_id,
+		is_private, follower_count, following_count,
+		roles.id, roles.name, roles.description, roles.level
 	FROM users 
 	LEFT JOIN roles ON (users.role_id = roles.id)
 	WHERE users.id = $1 AND users.is_active = true`
@@ -107,6 +136,18 @@ func (s *UserStore) GetByID(ctx context.Context, id int64) (*User, error) {
 		&user.Email,
 		&user.Password.hash,
 		&user.CreatedAt,
+		&user.TOTPEnabled,
+		&user.TOTPSecret,
+		&user.DisplayName,
+		&user.Bio,
+		&user.Website,
+		&user.Location,
+		&user.Birthday,
+		&user.BirthdayVisibility,
+		&user.AvatarID,
+		&user.IsPrivate,
+		&user.FollowerCount,
+		&user.FollowingCount,
 		&user.Role.ID,
 		&user.Role.Name,
 		&user.Role.Description,
@@ -217,7 +258,14 @@ func (s *UserStore) update(ctx context.Context, tx *sql.Tx, user *User) error {
 
 	_, err := tx.ExecContext(ctx, query, user.Username, user.Email, user.IsActive, user.ID)
 	if err != nil {
-		return err
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
+			return ErrDuplicateEmail
+		case err.Error() == `pq: duplicate key value violates unique constraint "users_username_key"`:
+			return ErrDuplicateUsername
+		default:
+			return err
+		}
 	}
 
 	return nil
@@ -267,20 +315,131 @@ func (s *UserStore) delete(ctx context.Context, tx *sql.Tx, id int64) error {
 
 func (s *UserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-	SELECT id, username, email, password, created_at FROM users 
+	SELECT id, username, email, password, created_at, totp_enabled, COALESCE(totp_secret, ''), deactivated_at FROM users
 	WHERE email = $1 AND is_active = true
 	`
 
+	return s.getCredentials(ctx, query, email)
+}
+
+// GetDeactivatedByEmail returns a user whose account is deactivated and
+// awaiting deletion.
+func (s *UserStore) GetDeactivatedByEmail(ctx context.Context, email string) (*User, error) {
+	query := `
+	SELECT id, username, email, password, created_at, totp_enabled, COALESCE(totp_secret, ''), deactivated_at FROM users
+	WHERE email = $1 AND is_active = false AND delete_after IS NOT NULL
+	`
+
+	return s.getCredentials(ctx, query, email)
+}
+
+// GetDeactivatedByID is GetDeactivatedByEmail for a user known by ID, as when
+// a login awaits its second factor.
+func (s *UserStore) GetDeactivatedByID(ctx context.Context, id int64) (*User, error) {
+	query := `
+	SELECT id, username, email, password, created_at, totp_enabled, COALESCE(totp_secret, ''), deactivated_at FROM users
+	WHERE id = $1 AND is_active = false AND delete_after IS NOT NULL
+	`
+
+	return s.getCredentials(ctx, query, id)
+}
+
+// getCredentials returns the user found by query with the fields needed to
+// log in.
+func (s *UserStore) getCredentials(ctx context.Context, query string, arg any) (*User, error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
 	user := &User{}
-	err := s.db.QueryRowContext(ctx, query, email).Scan(
+	err := s.db.QueryRowContext(ctx, query, arg).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.Password.hash,
 		&user.CreatedAt,
+		&user.TOTPEnabled,
+		&user.TOTPSecret,
+		&user.DeactivatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+func (s *UserStore) CreatePasswordReset(ctx context.Context, userID int64, token string, exp time.Duration) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// only the most recently requested link stays valid
+		if err := s.deletePasswordResets(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO password_resets (token, user_id, expiry) VALUES ($1, $2, $3)`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		_, err := tx.ExecContext(ctx, query, hashToken(token), userID, time.Now().Add(exp))
+		return err
+	})
+}
+
+// ResetPassword sets a new password for the owner of a valid reset token,
+// consumes every reset token of that user and revokes their sessions. It
+// returns the ID of the user.
+func (s *UserStore) ResetPassword(ctx context.Context, token, newPassword string) (int64, error) {
+	var userID int64
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		user, err := s.getUserFromPasswordReset(ctx, tx, token)
+		if err != nil {
+			return err
+		}
+
+		userID = user.ID
+
+		if err := user.Password.Set(newPassword); err != nil {
+			return err
+		}
+
+		if err := s.updatePassword(ctx, tx, user); err != nil {
+			return err
+		}
+
+		if err := s.deletePasswordResets(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		return s.revokeSessions(ctx, tx, user.ID)
+	})
+
+	return userID, err
+}
+
+func (s *UserStore) getUserFromPasswordReset(ctx context.Context, tx *sql.Tx, token string) (*User, error) {
+	query := `
+	SELECT u.id, u.username, u.email, u.created_at, u.is_active
+	FROM users u
+	JOIN password_resets pr ON u.id = pr.user_id
+	WHERE pr.token = $1 AND pr.expiry > $2 AND u.is_active = true
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	user := &User{}
+	err := tx.QueryRowContext(ctx, query, hashToken(token), time.Now()).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.CreatedAt,
+		&user.IsActive,
 	)
 	if err != nil {
 		switch {
@@ -293,3 +452,447 @@ func (s *UserStore) GetByEmail(ctx context.Context, email string) (*User, error)
 
 	return user, nil
 }
+
+func (s *UserStore) updatePassword(ctx context.Context, tx *sql.Tx, user *User) error {
+	query := `UPDATE users SET password = $1 WHERE id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, user.Password.hash, user.ID)
+	return err
+}
+
+func (s *UserStore) deletePasswordResets(ctx context.Context, tx *sql.Tx, userID int64) error {
+	query := `DELETE FROM password_resets WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, userID)
+	return err
+}
+
+func (s *UserStore) revokeSessions(ctx context.Context, tx *sql.Tx, userID int64) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, userID)
+	return err
+}
+
+// ReissueInvitation replaces the invitations of a user who has not activated
+// their account yet. token is expected to be hashed already.
+func (s *UserStore) ReissueInvitation(ctx context.Context, email, token string, invitationExp time.Duration) (*User, error) {
+	user := &User{}
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `SELECT id, username, email, created_at, is_active FROM users WHERE email = $1 AND is_active = false`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		err := tx.QueryRowContext(ctx, query, email).Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.CreatedAt,
+			&user.IsActive,
+		)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if err := s.deleteUserInvitations(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		return s.createUserInvitation(ctx, tx, token, invitationExp, user.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// DeleteUnactivated removes users that registered before the cutoff and never
+// activated their account, along with their invitations.
+func (s *UserStore) DeleteUnactivated(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+	WITH stale AS (
+		SELECT id FROM users WHERE is_active = false AND deactivated_at IS NULL AND created_at < $1
+	), invitations AS (
+		DELETE FROM user_invitations WHERE user_id IN (SELECT id FROM stale)
+	)
+	DELETE FROM users WHERE id IN (SELECT id FROM stale)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// CreateEmailChange stores newEmail as pending until the token sent to it is
+// confirmed. Only the latest request of a user stays valid.
+func (s *UserStore) CreateEmailChange(ctx context.Context, userID int64, newEmail, token string, exp time.Duration) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := s.deleteEmailChanges(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO email_changes (token, user_id, new_email, expiry) VALUES ($1, $2, $3, $4)`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		_, err := tx.ExecContext(ctx, query, hashToken(token), userID, newEmail, time.Now().Add(exp))
+		return err
+	})
+}
+
+// ConfirmEmailChange switches the user to the pending email of a valid token
+// and returns the updated user together with the previous email. The unique
+// constraint on users.email is checked again here, since the address may have
+// been taken after the change was requested.
+func (s *UserStore) ConfirmEmailChange(ctx context.Context, token string) (*User, string, error) {
+	user := &User{}
+	var oldEmail string
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+		SELECT u.id, u.username, u.email, ec.new_email, u.created_at, u.is_active
+		FROM users u
+		JOIN email_changes ec ON u.id = ec.user_id
+		WHERE ec.token = $1 AND ec.expiry > $2
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		err := tx.QueryRowContext(ctx, query, hashToken(token), time.Now()).Scan(
+			&user.ID,
+			&user.Username,
+			&oldEmail,
+			&user.Email,
+			&user.CreatedAt,
+			&user.IsActive,
+		)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if err := s.update(ctx, tx, user); err != nil {
+			return err
+		}
+
+		return s.deleteEmailChanges(ctx, tx, user.ID)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return user, oldEmail, nil
+}
+
+func (s *UserStore) deleteEmailChanges(ctx context.Context, tx *sql.Tx, userID int64) error {
+	query := `DELETE FROM email_changes WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, userID)
+	return err
+}
+
+// CreateWithIdentity creates an already active user for a verified external
+// identity and links the two.
+func (s *UserStore) CreateWithIdentity(ctx context.Context, user *User, identity *Identity) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := s.Create(ctx, tx, user); err != nil {
+			return err
+		}
+
+		user.IsActive = true
+		if err := s.update(ctx, tx, user); err != nil {
+			return err
+		}
+
+		identity.UserID = user.ID
+
+		return createIdentity(ctx, tx, identity)
+	})
+}
+
+func (s *UserStore) UpdateProfile(ctx context.Context, user *User) error {
+	query := `
+	UPDATE users
+	SET display_name = $1, bio = $2, website = $3, location = $4, birthday = $5, birthday_visibility = $6,
+		avatar_media_id = $7, is_private = $8
+	WHERE id = $9 AND is_active = true
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(
+		ctx,
+		query,
+		user.DisplayName,
+		user.Bio,
+		user.Website,
+		user.Location,
+		user.Birthday,
+		user.BirthdayVisibility,
+		user.AvatarID,
+		user.IsPrivate,
+		user.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// authorShown is the SQL condition for the posts and comments of the user
+// joined as alias to be shown. Those of deactivated accounts are hidden, the
+// ghost user keeps anonymized ones readable.
+func authorShown(alias string) string {
+	return fmt.Sprintf(`(%[1]s.is_active OR %[1]s.is_ghost)`, alias)
+}
+
+// CanViewPosts reports whether viewerID may see the posts of authorID: the
+// author's account is not deactivated, the author has not blocked the viewer
+// and, for a private account, the viewer is the author or an approved
+// follower.
+func (s *UserStore) CanViewPosts(ctx context.Context, authorID, viewerID int64) (bool, error) {
+	query := `
+	SELECT ` + authorShown("u") + `
+		AND NOT EXISTS (SELECT 1 FROM blocks WHERE blocker_id = u.id AND blocked_id = $2)
+		AND (NOT u.is_private OR u.id = $2
+			OR EXISTS (SELECT 1 FROM followers WHERE user_id = $2 AND follower_id = u.id))
+	FROM users u
+	WHERE u.id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var visible bool
+	err := s.db.QueryRowContext(ctx, query, authorID, viewerID).Scan(&visible)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+
+	return visible, nil
+}
+
+// DeletedUsername is the name of the ghost user anonymized posts and comments
+// are moved to when an account is purged. The ghost is found by its is_ghost
+// flag, the name is reserved so no one can pose as it.
+const DeletedUsername = "[deleted]"
+
+// Deactivate hides the account, logs it out everywhere and schedules its
+// deletion. Until then Reactivate restores it.
+func (s *UserStore) Deactivate(ctx context.Context, userID int64, deleteAfter time.Time) error {
+	query := `
+	UPDATE users SET is_active = false, deactivated_at = NOW(), delete_after = $2
+	WHERE id = $1 AND is_active = true
+	`
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		res, err := tx.ExecContext(ctx, query, userID, deleteAfter)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		return s.revokeSessions(ctx, tx, userID)
+	})
+}
+
+func (s *UserStore) Reactivate(ctx context.Context, userID int64) error {
+	query := `
+	UPDATE users SET is_active = true, deactivated_at = NULL, delete_after = NULL
+	WHERE id = $1 AND is_active = false AND delete_after IS NOT NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetDueForDeletion returns up to limit deactivated users whose grace period
+// ended before now.
+func (s *UserStore) GetDueForDeletion(ctx context.Context, now time.Time, limit int) ([]int64, error) {
+	query := `
+	SELECT id FROM users
+	WHERE is_active = false AND delete_after <= $1
+	ORDER BY delete_after
+	LIMIT $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// Purge permanently deletes a deactivated account. Its posts and comments are
+// moved to the ghost user when anonymize is set and removed otherwise, and
+// the follower counts of the users it followed or was followed by and the
+// reaction counts of what it reacted to are updated. It returns the user's
+// uploads, whose blobs the caller removes.
+func (s *UserStore) Purge(ctx context.Context, userID int64, anonymize bool) ([]Media, error) {
+	var uploads []Media
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		// lock the row so a concurrent login cannot restore the account
+		var id int64
+		err := tx.QueryRowContext(
+			ctx,
+			`SELECT id FROM users WHERE id = $1 AND is_active = false AND delete_after <= NOW() FOR UPDATE`,
+			userID,
+		).Scan(&id)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if uploads, err = getUserMedia(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		exec := func(query string, args ...any) error {
+			_, err := tx.ExecContext(ctx, query, args...)
+			return err
+		}
+
+		// reposts carry nothing of the user's to keep
+		if err := exec(`DELETE FROM posts WHERE user_id = $1 AND repost_of_id IS NOT NULL`, userID); err != nil {
+			return err
+		}
+
+		if anonymize {
+			ghost := `(SELECT id FROM users WHERE is_ghost)`
+
+			if err := exec(`UPDATE comments SET user_id = `+ghost+` WHERE user_id = $1`, userID); err != nil {
+				return err
+			}
+
+			if err := exec(`UPDATE posts SET user_id = `+ghost+` WHERE user_id = $1`, userID); err != nil {
+				return err
+			}
+		} else {
+			if err := exec(`DELETE FROM comments WHERE user_id = $1 OR post_id IN (SELECT id FROM posts WHERE user_id = $1)`, userID); err != nil {
+				return err
+			}
+
+			if err := exec(`DELETE FROM posts WHERE user_id = $1`, userID); err != nil {
+				return err
+			}
+		}
+
+		for _, query := range []string{
+			`UPDATE users SET follower_count = follower_count - 1
+			WHERE id IN (SELECT follower_id FROM followers WHERE user_id = $1)`,
+			`UPDATE users SET following_count = following_count - 1
+			WHERE id IN (SELECT user_id FROM followers WHERE follower_id = $1)`,
+			`UPDATE posts SET reaction_counts = ` + reactionCountsDelta("r.emoji", "-1") + `
+			FROM post_reactions r WHERE r.post_id = posts.id AND r.user_id = $1`,
+			`UPDATE comments SET reaction_counts = ` + reactionCountsDelta("r.emoji", "-1") + `
+			FROM comment_reactions r WHERE r.comment_id = comments.id AND r.user_id = $1`,
+			`DELETE FROM user_invitations WHERE user_id = $1`,
+			`DELETE FROM users WHERE id = $1`,
+		} {
+			if err := exec(query, userID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return uploads, err
+}